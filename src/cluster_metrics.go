@@ -0,0 +1,431 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const (
+	// instancePairSample is the sample type of metrics of an instance towards another
+	// instance of the cluster
+	instancePairSample = "OracleInstancePairSample"
+	// clusterEnabledQuery turns the global cache groups off on single instance databases
+	clusterEnabledQuery = `SELECT COUNT(*) FROM gv$parameter WHERE NAME = 'cluster_database' AND UPPER(VALUE) = 'TRUE'`
+)
+
+var oracleCluster = oracleMetricGroup{
+	name:       "cluster",
+	entityType: "ora-cluster",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			d.DB_UNIQUE_NAME,
+			i.INST_ID,
+			i.INSTANCE_NAME,
+			i.HOST_NAME,
+			i.STATUS,
+			p.VALUE AS CLUSTER_DATABASE
+		FROM v$database d, gv$instance i, gv$parameter p
+		WHERE p.INST_ID = i.INST_ID AND p.NAME = 'cluster_database'
+		ORDER BY i.INST_ID`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "cluster.instances",
+			identifier:    "INSTANCES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "cluster.openInstances",
+			identifier:    "OPEN_INSTANCES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "cluster.instanceNames",
+			identifier:    "INSTANCE_NAMES",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "cluster.hostNames",
+			identifier:    "HOST_NAMES",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: clusterMetricsGenerator,
+}
+
+// clusterMetricsGenerator reports the instances of a RAC database on its cluster
+// entity. Nothing is reported for single instance databases
+func clusterMetricsGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	var clusterName string
+	var isCluster bool
+	var instanceNames, hostNames []string
+	openInstances := 0
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		clusterName = stringValue(rowMap["DB_UNIQUE_NAME"])
+		isCluster = strings.EqualFold(stringValue(rowMap["CLUSTER_DATABASE"]), "TRUE")
+		instanceNames = append(instanceNames, stringValue(rowMap["INSTANCE_NAME"]))
+		hostNames = append(hostNames, stringValue(rowMap["HOST_NAME"]))
+		if stringValue(rowMap["STATUS"]) == "OPEN" {
+			openInstances++
+		}
+	}
+
+	if !isCluster || clusterName == "" {
+		return nil
+	}
+
+	sort.Strings(hostNames)
+	values := map[string]interface{}{
+		"INSTANCES":      len(instanceNames),
+		"OPEN_INSTANCES": openInstances,
+		"INSTANCE_NAMES": strings.Join(instanceNames, ","),
+		"HOST_NAMES":     strings.Join(hostNames, ","),
+	}
+
+	for _, metric := range metrics {
+		if metricEnabled(metric) {
+			metricChan <- newrelicMetricSender{
+				metric: &newrelicMetric{
+					name:       metric.name,
+					metricType: metric.metricType,
+					value:      values[metric.identifier],
+				},
+				metadata: map[string]string{"cluster": clusterName},
+			}
+		}
+	}
+
+	return nil
+}
+
+var oracleGCInstancePairs = oracleMetricGroup{
+	name:         "gc_instance_pairs",
+	optIn:        true,
+	enabledQuery: clusterEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Blocks received are counted in bytes with the default block size of the instance
+		return `
+		SELECT
+			t.INST_ID,
+			t.INSTANCE AS REMOTE_INST_ID,
+			SUM(t.CR_BLOCK) AS CR_BLOCK,
+			SUM(t.CR_BUSY) AS CR_BUSY,
+			SUM(t.CR_CONGESTED) AS CR_CONGESTED,
+			SUM(t.CR_BLOCK_TIME) AS CR_BLOCK_TIME,
+			SUM(t.CURRENT_BLOCK) AS CURRENT_BLOCK,
+			SUM(t.CURRENT_BUSY) AS CURRENT_BUSY,
+			SUM(t.CURRENT_CONGESTED) AS CURRENT_CONGESTED,
+			SUM(t.CURRENT_BLOCK_TIME) AS CURRENT_BLOCK_TIME,
+			(SUM(t.CR_BLOCK) + SUM(t.CURRENT_BLOCK)) * MAX(TO_NUMBER(p.VALUE)) AS BYTES_RECEIVED
+		FROM gv$instance_cache_transfer t
+		JOIN gv$parameter p ON p.INST_ID = t.INST_ID AND p.NAME = 'db_block_size'
+		WHERE t.INSTANCE <> t.INST_ID
+		GROUP BY t.INST_ID, t.INSTANCE`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "gc.crBlocksReceivedPerSecond",
+			identifier:    "CR_BLOCK",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.crBlocksBusyPerSecond",
+			identifier:    "CR_BUSY",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.crBlocksCongestedPerSecond",
+			identifier:    "CR_CONGESTED",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.crBlockReceiveTimeInMicroseconds",
+			identifier:    "CR_BLOCK_TIME",
+			metricType:    metric.DELTA,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.currentBlocksReceivedPerSecond",
+			identifier:    "CURRENT_BLOCK",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.currentBlocksBusyPerSecond",
+			identifier:    "CURRENT_BUSY",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.currentBlocksCongestedPerSecond",
+			identifier:    "CURRENT_CONGESTED",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.currentBlockReceiveTimeInMicroseconds",
+			identifier:    "CURRENT_BLOCK_TIME",
+			metricType:    metric.DELTA,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.bytesReceivedPerSecond",
+			identifier:    "BYTES_RECEIVED",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			"instanceID":      getInstanceIDString(row["INST_ID"]),
+			sampleMetadataKey: instancePairSample,
+			dimensionMetadataPrefix + remoteInstanceDimension: getInstanceIDString(row["REMOTE_INST_ID"]),
+		}
+	}),
+}
+
+var oracleGCBlockServer = oracleMetricGroup{
+	name:         "gc_block_server",
+	optIn:        true,
+	enabledQuery: clusterEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT INST_ID, CR_REQUESTS, CURRENT_REQUESTS, DATA_REQUESTS, UNDO_REQUESTS, TX_REQUESTS, FLUSHES
+		FROM gv$cr_block_server`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "gc.server.crRequestsPerSecond",
+			identifier:    "CR_REQUESTS",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.server.currentRequestsPerSecond",
+			identifier:    "CURRENT_REQUESTS",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.server.dataRequestsPerSecond",
+			identifier:    "DATA_REQUESTS",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.server.undoRequestsPerSecond",
+			identifier:    "UNDO_REQUESTS",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.server.txRequestsPerSecond",
+			identifier:    "TX_REQUESTS",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.server.flushesPerSecond",
+			identifier:    "FLUSHES",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+	},
+
+	metricsGenerator: columnMetricsGenerator,
+}
+
+var oracleGCBlocks = oracleMetricGroup{
+	name:         "gc_blocks",
+	optIn:        true,
+	enabledQuery: clusterEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		query := `
+		SELECT INST_ID, NAME, VALUE
+		FROM gv$sysstat
+		WHERE`
+		query += inMetrics("NAME", metrics)
+		return query
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "gc.blocksLost",
+			identifier:    "gc blocks lost",
+			metricType:    metric.DELTA,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.blocksCorrupt",
+			identifier:    "gc blocks corrupt",
+			metricType:    metric.DELTA,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.crBlocksServedPerSecond",
+			identifier:    "gc cr blocks served",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.currentBlocksServedPerSecond",
+			identifier:    "gc current blocks served",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+	},
+
+	metricsGenerator: rowMetricsGenerator,
+}
+
+var oracleGCMessages = oracleMetricGroup{
+	name:         "gc_messages",
+	optIn:        true,
+	enabledQuery: clusterEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		query := `
+		SELECT INST_ID, NAME, VALUE
+		FROM gv$dlm_misc
+		WHERE`
+		query += inMetrics("NAME", metrics)
+		return query
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "gc.messagesSentDirectlyPerSecond",
+			identifier:    "messages sent directly",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.messagesSentIndirectlyPerSecond",
+			identifier:    "messages sent indirectly",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.messagesFlowControlledPerSecond",
+			identifier:    "messages flow controlled",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.messagesReceivedPerSecond",
+			identifier:    "messages received logical",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.gcsMessagesReceivedPerSecond",
+			identifier:    "gcs msgs received",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "gc.gesMessagesReceivedPerSecond",
+			identifier:    "ges msgs received",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+	},
+
+	metricsGenerator: rowMetricsGenerator,
+}
+
+var oracleGCInterconnects = oracleMetricGroup{
+	name:         "gc_interconnects",
+	optIn:        true,
+	enabledQuery: clusterEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT INST_ID, NAME, IP_ADDRESS
+		FROM gv$cluster_interconnects
+		ORDER BY INST_ID, NAME`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "gc.interconnectNames",
+			identifier:    "NAME",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "gc.interconnectAddresses",
+			identifier:    "IP_ADDRESS",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: func(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+		columnNames, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve columns from rows")
+		}
+
+		// Instances with several interconnects report them as a comma separated list
+		var instanceIDs []string
+		values := make(map[string]map[string][]string)
+		for rows.Next() {
+			rowMap, err := scanRowMap(rows, columnNames)
+			if err != nil {
+				return err
+			}
+
+			instanceID := getInstanceIDString(rowMap["INST_ID"])
+			if _, ok := values[instanceID]; !ok {
+				instanceIDs = append(instanceIDs, instanceID)
+				values[instanceID] = make(map[string][]string)
+			}
+			for _, metric := range metrics {
+				values[instanceID][metric.identifier] = append(values[instanceID][metric.identifier], stringValue(rowMap[metric.identifier]))
+			}
+		}
+
+		for _, instanceID := range instanceIDs {
+			for _, metric := range metrics {
+				if metricEnabled(metric) {
+					metricChan <- newrelicMetricSender{
+						metric: &newrelicMetric{
+							name:       metric.name,
+							metricType: metric.metricType,
+							value:      strings.Join(values[instanceID][metric.identifier], ","),
+						},
+						metadata: map[string]string{"instanceID": instanceID},
+					}
+				}
+			}
+		}
+
+		return nil
+	},
+}
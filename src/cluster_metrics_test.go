@@ -0,0 +1,139 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+func TestClusterMetrics(t *testing.T) {
+	columns := []string{"DB_UNIQUE_NAME", "INST_ID", "INSTANCE_NAME", "HOST_NAME", "STATUS", "CLUSTER_DATABASE"}
+
+	senders := collectMetricGroup(t, oracleCluster, sqlmock.NewRows(columns).
+		AddRow("ORCL", 1, "ORCL1", "node2", "OPEN", "TRUE").
+		AddRow("ORCL", 2, "ORCL2", "node1", "MOUNTED", "TRUE"))
+
+	expected := map[string]interface{}{
+		"cluster.instances":     2,
+		"cluster.openInstances": 1,
+		"cluster.instanceNames": "ORCL1,ORCL2",
+		"cluster.hostNames":     "node1,node2",
+	}
+	if len(senders) != len(expected) {
+		t.Fatalf("Expected %d metrics, got %d", len(expected), len(senders))
+	}
+	for _, sender := range senders {
+		if sender.metadata["cluster"] != "ORCL" {
+			t.Errorf("Expected metric %s on cluster ORCL, got %v", sender.metric.name, sender.metadata)
+		}
+		if sender.metric.value != expected[sender.metric.name] {
+			t.Errorf("Expected %s=%v, got %v", sender.metric.name, expected[sender.metric.name], sender.metric.value)
+		}
+	}
+
+	senders = collectMetricGroup(t, oracleCluster, sqlmock.NewRows(columns).
+		AddRow("ORCL", 1, "ORCL", "node1", "OPEN", "FALSE"))
+	if len(senders) != 0 {
+		t.Errorf("Expected no cluster metrics for a single instance database, got %d", len(senders))
+	}
+}
+
+func TestGCInstancePairMetrics(t *testing.T) {
+	columns := []string{"INST_ID", "REMOTE_INST_ID", "CR_BLOCK", "CR_BUSY", "CR_CONGESTED", "CR_BLOCK_TIME", "CURRENT_BLOCK", "CURRENT_BUSY", "CURRENT_CONGESTED", "CURRENT_BLOCK_TIME", "BYTES_RECEIVED"}
+
+	senders := collectMetricGroup(t, oracleGCInstancePairs, sqlmock.NewRows(columns).
+		AddRow(1, 2, 100, 1, 2, 5000, 50, 3, 4, 7000, 150*8192))
+	if len(senders) != 7 {
+		t.Fatalf("Expected 7 default metrics, got %d", len(senders))
+	}
+	for _, sender := range senders {
+		if sender.metadata["instanceID"] != "1" || sender.metadata[sampleMetadataKey] != instancePairSample || sender.metadata[dimensionMetadataPrefix+remoteInstanceDimension] != "2" {
+			t.Errorf("Unexpected metadata %v", sender.metadata)
+		}
+		if value, _ := toFloat64(sender.metric.value); sender.metric.name == "gc.bytesReceivedPerSecond" && value != 150*8192 {
+			t.Errorf("Expected %d bytes received, got %v", 150*8192, sender.metric.value)
+		}
+	}
+}
+
+func TestPopulateMetrics_ClusterAndInstancePairs(t *testing.T) {
+	args = argumentList{
+		Hostname:    "testhost",
+		Port:        "1234",
+		ServiceName: "testServiceName",
+	}
+	defer func() { args = argumentList{} }()
+
+	pairMetadata := func(remote string) map[string]string {
+		return map[string]string{
+			"instanceID":      "1",
+			sampleMetadataKey: instancePairSample,
+			dimensionMetadataPrefix + remoteInstanceDimension: remote,
+		}
+	}
+
+	i, _ := integration.New("oracletest", "0.0.1")
+	metricChan := make(chan newrelicMetricSender, 4)
+	metricChan <- newrelicMetricSender{
+		metric:   &newrelicMetric{name: "cluster.instances", value: 3, metricType: metric.GAUGE},
+		metadata: map[string]string{"cluster": "ORCL"},
+	}
+	metricChan <- newrelicMetricSender{
+		metric:   &newrelicMetric{name: "gc.crBlocksCongestedPerSecond", value: 1, metricType: metric.GAUGE},
+		metadata: pairMetadata("2"),
+	}
+	metricChan <- newrelicMetricSender{
+		metric:   &newrelicMetric{name: "gc.currentBlocksCongestedPerSecond", value: 2, metricType: metric.GAUGE},
+		metadata: pairMetadata("2"),
+	}
+	metricChan <- newrelicMetricSender{
+		metric:   &newrelicMetric{name: "gc.crBlocksCongestedPerSecond", value: 3, metricType: metric.GAUGE},
+		metadata: pairMetadata("3"),
+	}
+	close(metricChan)
+
+	populateMetrics(metricChan, i, map[string]string{"1": "one", "2": "two"})
+
+	marshalled, err := i.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedJSON := `{"name":"oracletest","protocol_version":"3","integration_version":"0.0.1","data":[{"entity":{"name":"ORCL","type":"ora-cluster","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[{"cluster.instances":3,"displayName":"ORCL","entityName":"ora-cluster:ORCL","event_type":"OracleClusterSample","reportingEndpoint":"testhost:1234"}],"inventory":{},"events":[]},{"entity":{"name":"one","type":"ora-instance","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[{"displayName":"one","entityName":"ora-instance:one","event_type":"OracleInstancePairSample","gc.crBlocksCongestedPerSecond":1,"gc.currentBlocksCongestedPerSecond":2,"remoteInstance":"two","reportingEndpoint":"testhost:1234"},{"displayName":"one","entityName":"ora-instance:one","event_type":"OracleInstancePairSample","gc.crBlocksCongestedPerSecond":3,"remoteInstance":"3","reportingEndpoint":"testhost:1234"}],"inventory":{},"events":[]}]}`
+	if string(marshalled) != expectedJSON {
+		t.Errorf("Expected %s, got %s", expectedJSON, marshalled)
+	}
+}
+
+func TestGCGroups_SingleInstance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbWrapper := database.NewDBWrapper(sqlx.NewDb(db, "sqlmock"))
+
+	for _, group := range []oracleMetricGroup{oracleGCInstancePairs, oracleGCBlockServer, oracleGCBlocks, oracleGCMessages, oracleGCInterconnects} {
+		// Only the enabled query runs, the query of the group would fail the expectations
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM gv\\$parameter WHERE NAME = 'cluster_database'").
+			WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		metricChan := make(chan newrelicMetricSender, 10)
+		group.Collect(dbWrapper, &wg, metricChan)
+		close(metricChan)
+
+		if len(metricChan) != 0 {
+			t.Errorf("Expected no metrics from %s on a single instance database, got %d", group.name, len(metricChan))
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const (
+	latchSample         = "OracleLatchSample"
+	mutexSleepSample    = "OracleMutexSleepSample"
+	enqueueSample       = "OracleEnqueueSample"
+	counterKeySeparator = "|"
+)
+
+var oracleLatches = oracleMetricGroup{
+	name:  "latches",
+	optIn: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			INST_ID,
+			NAME,
+			GETS,
+			MISSES,
+			SLEEPS,
+			IMMEDIATE_GETS,
+			IMMEDIATE_MISSES,
+			SPIN_GETS,
+			WAIT_TIME / 1000 AS WAIT_TIME_MS
+		FROM gv$latch
+		WHERE MISSES > 0 OR IMMEDIATE_MISSES > 0 OR SLEEPS > 0`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "latch.sleepsPerSecond",
+			identifier:    "SLEEPS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "latch.missesPerSecond",
+			identifier:    "MISSES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "latch.getsPerSecond",
+			identifier:    "GETS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "latch.immediateGetsPerSecond",
+			identifier:    "IMMEDIATE_GETS",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "latch.immediateMissesPerSecond",
+			identifier:    "IMMEDIATE_MISSES",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "latch.spinGetsPerSecond",
+			identifier:    "SPIN_GETS",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "latch.waitTimeInMillisecondsPerSecond",
+			identifier:    "WAIT_TIME_MS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: counterRatesGenerator("latches", func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			sampleMetadataKey:                 latchSample,
+			dimensionMetadataPrefix + "latch": stringValue(row["NAME"]),
+		}
+	}, "SLEEPS", "MISSES"),
+}
+
+var oracleMutexSleeps = oracleMetricGroup{
+	name:  "mutex_sleeps",
+	optIn: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			INST_ID,
+			MUTEX_TYPE,
+			LOCATION,
+			SUM(SLEEPS) AS SLEEPS,
+			SUM(WAIT_TIME) / 1000 AS WAIT_TIME_MS
+		FROM gv$mutex_sleep
+		GROUP BY INST_ID, MUTEX_TYPE, LOCATION`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "mutex.sleepsPerSecond",
+			identifier:    "SLEEPS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "mutex.waitTimeInMillisecondsPerSecond",
+			identifier:    "WAIT_TIME_MS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: counterRatesGenerator("mutex-sleeps", func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			sampleMetadataKey:                     mutexSleepSample,
+			dimensionMetadataPrefix + "mutexType": stringValue(row["MUTEX_TYPE"]),
+			dimensionMetadataPrefix + "location":  stringValue(row["LOCATION"]),
+		}
+	}, "SLEEPS", "WAIT_TIME_MS"),
+}
+
+var oracleEnqueues = oracleMetricGroup{
+	name:  "enqueues",
+	optIn: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			INST_ID,
+			EQ_TYPE,
+			TOTAL_REQ# AS REQUESTS,
+			TOTAL_WAIT# AS WAITS,
+			SUCC_REQ# AS SUCCESSFUL_REQUESTS,
+			FAILED_REQ# AS FAILED_REQUESTS,
+			CUM_WAIT_TIME AS WAIT_TIME_MS
+		FROM gv$enqueue_stat
+		WHERE TOTAL_REQ# > 0`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "enqueue.requestsPerSecond",
+			identifier:    "REQUESTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "enqueue.waitsPerSecond",
+			identifier:    "WAITS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "enqueue.successfulRequestsPerSecond",
+			identifier:    "SUCCESSFUL_REQUESTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "enqueue.failedRequestsPerSecond",
+			identifier:    "FAILED_REQUESTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "enqueue.waitTimeInMillisecondsPerSecond",
+			identifier:    "WAIT_TIME_MS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: counterRatesGenerator("enqueues", func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			sampleMetadataKey:                       enqueueSample,
+			dimensionMetadataPrefix + "enqueueType": stringValue(row["EQ_TYPE"]),
+		}
+	}, "WAITS", "REQUESTS"),
+}
+
+// counterEntry is a row of cumulative counters, such as a latch, with their rates
+type counterEntry struct {
+	metadata map[string]string
+	counters map[string]float64
+	rates    map[string]float64
+}
+
+// counterRatesGenerator returns a metrics generator for rows of cumulative counters
+// that reports the per second rate of every counter since the previous run. The
+// counters are kept in the state store per instance, so nothing is reported on the
+// first run. When rankBy is set, only the CONTENTION_TOP_N rows with the highest rates
+// of the rankBy identifiers are reported, and rows whose rates are all zero are left out
+func counterRatesGenerator(group string, metadata func(map[string]interface{}) map[string]string, rankBy ...string) func(database.Rows, []*oracleMetric, chan<- newrelicMetricSender) error {
+	return func(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+		columnNames, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve columns from rows")
+		}
+
+		var instanceIDs []string
+		entries := make(map[string]map[string]*counterEntry)
+		for rows.Next() {
+			rowMap, err := scanRowMap(rows, columnNames)
+			if err != nil {
+				return err
+			}
+
+			instanceID := getInstanceIDString(rowMap["INST_ID"])
+			if _, ok := entries[instanceID]; !ok {
+				instanceIDs = append(instanceIDs, instanceID)
+				entries[instanceID] = make(map[string]*counterEntry)
+			}
+
+			entry := &counterEntry{metadata: metadata(rowMap), counters: make(map[string]float64)}
+			entry.metadata["instanceID"] = instanceID
+			for _, metric := range metrics {
+				if value, ok := toFloat64(sanitizedValue(rowMap[metric.identifier])); ok {
+					entry.counters[metric.identifier] = value
+				}
+			}
+			entries[instanceID][counterEntryKey(entry.metadata)] = entry
+		}
+
+		now := time.Now()
+		for _, instanceID := range instanceIDs {
+			counters := make(map[string]float64)
+			for key, entry := range entries[instanceID] {
+				for identifier, value := range entry.counters {
+					counters[key+counterKeySeparator+identifier] = value
+				}
+			}
+
+			deltas, elapsed := counterDeltas(stateKey("counter-rates-"+group, instanceID), counters, now)
+			if elapsed <= 0 {
+				continue
+			}
+
+			var ranked []*counterEntry
+			for key, entry := range entries[instanceID] {
+				entry.rates = make(map[string]float64)
+				active := false
+				for identifier := range entry.counters {
+					if delta, ok := deltas[key+counterKeySeparator+identifier]; ok {
+						entry.rates[identifier] = delta / elapsed
+						active = active || delta > 0
+					}
+				}
+				if active || (len(rankBy) == 0 && len(entry.rates) > 0) {
+					ranked = append(ranked, entry)
+				}
+			}
+			limit := 0
+			if len(rankBy) > 0 {
+				limit = args.ContentionTopN
+			}
+
+			for _, entry := range topCounterEntries(ranked, rankBy, limit) {
+				for _, metric := range metrics {
+					if rate, ok := entry.rates[metric.identifier]; ok && metricEnabled(metric) {
+						metricChan <- newrelicMetricSender{
+							metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: rate},
+							metadata: entry.metadata,
+						}
+					}
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+// counterEntryKey identifies a row of counters within its instance by its dimensions
+func counterEntryKey(metadata map[string]string) string {
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, metadata[name])
+	}
+	return strings.Join(values, counterKeySeparator)
+}
+
+// topCounterEntries sorts entries by the rates of the rankBy identifiers, the first
+// one taking precedence, and returns the first n of them. Every entry is returned
+// when n is not positive
+func topCounterEntries(entries []*counterEntry, rankBy []string, n int) []*counterEntry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		for _, identifier := range rankBy {
+			if entries[i].rates[identifier] != entries[j].rates[identifier] {
+				return entries[i].rates[identifier] > entries[j].rates[identifier]
+			}
+		}
+		return counterEntryKey(entries[i].metadata) < counterEntryKey(entries[j].metadata)
+	})
+
+	if n > 0 && len(entries) > n {
+		return entries[:n]
+	}
+	return entries
+}
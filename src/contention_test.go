@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+func TestCounterDeltas(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	now := time.Now()
+	if deltas, elapsed := counterDeltas("counters", map[string]float64{"a": 10, "b": 50}, now.Add(-10*time.Second)); deltas != nil || elapsed != 0 {
+		t.Errorf("Expected no deltas on the first run, got %v over %v", deltas, elapsed)
+	}
+
+	deltas, elapsed := counterDeltas("counters", map[string]float64{"a": 30, "b": 20, "c": 5}, now)
+	if elapsed != 10 {
+		t.Errorf("Expected 10 seconds elapsed, got %v", elapsed)
+	}
+	if len(deltas) != 1 || deltas["a"] != 20 {
+		t.Errorf("Expected only the delta of a, got %v", deltas)
+	}
+}
+
+func TestLatches(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+	defer func(previous argumentList) { args = previous }(args)
+	args = argumentList{ContentionTopN: 1}
+
+	columns := []string{"INST_ID", "NAME", "GETS", "MISSES", "SLEEPS", "IMMEDIATE_GETS", "IMMEDIATE_MISSES", "SPIN_GETS", "WAIT_TIME_MS"}
+	senders := collectMetricGroup(t, oracleLatches, sqlmock.NewRows(columns).
+		AddRow("1", "shared pool", 1000, 10, 5, 0, 0, 5, 2.5).
+		AddRow("1", "cache buffers chains", 5000, 40, 20, 0, 0, 20, 10.0))
+	if len(senders) != 0 {
+		t.Fatalf("Expected no metrics on the first run, got %d", len(senders))
+	}
+
+	rewindCounters(t, stateKey("counter-rates-latches", "1"), 10)
+
+	senders = collectMetricGroup(t, oracleLatches, sqlmock.NewRows(columns).
+		AddRow("1", "shared pool", 3000, 210, 105, 0, 0, 105, 52.5).
+		AddRow("1", "cache buffers chains", 6000, 50, 30, 0, 0, 20, 12.0))
+
+	values := make(map[string]float64)
+	for _, sender := range senders {
+		if sender.metadata[dimensionMetadataPrefix+"latch"] != "shared pool" || sender.metadata[sampleMetadataKey] != latchSample || sender.metadata["instanceID"] != "1" {
+			t.Errorf("Expected only the shared pool latch, got %v", sender.metadata)
+		}
+		values[sender.metric.name] = sender.metric.value.(float64)
+	}
+
+	expected := map[string]float64{
+		"latch.sleepsPerSecond":                 10,
+		"latch.missesPerSecond":                 20,
+		"latch.getsPerSecond":                   200,
+		"latch.waitTimeInMillisecondsPerSecond": 5,
+	}
+	if len(values) != len(expected) {
+		t.Errorf("Expected %d metrics, got %v", len(expected), values)
+	}
+	for name, value := range expected {
+		if values[name] < value-0.1 || values[name] > value+0.1 {
+			t.Errorf("Expected %s to be about %v, got %v", name, value, values[name])
+		}
+	}
+}
+
+func TestLatches_CounterReset(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+	defer func(previous argumentList) { args = previous }(args)
+	args = argumentList{ContentionTopN: 10}
+
+	columns := []string{"INST_ID", "NAME", "GETS", "MISSES", "SLEEPS", "IMMEDIATE_GETS", "IMMEDIATE_MISSES", "SPIN_GETS", "WAIT_TIME_MS"}
+	collectMetricGroup(t, oracleLatches, sqlmock.NewRows(columns).
+		AddRow("1", "shared pool", 5000, 50, 20, 0, 0, 20, 10.0))
+	rewindCounters(t, stateKey("counter-rates-latches", "1"), 10)
+
+	// The instance restarted, so every counter went down
+	senders := collectMetricGroup(t, oracleLatches, sqlmock.NewRows(columns).
+		AddRow("1", "shared pool", 100, 1, 0, 0, 0, 0, 0.0))
+	if len(senders) != 0 {
+		t.Fatalf("Expected no metrics after a counter reset, got %d", len(senders))
+	}
+	rewindCounters(t, stateKey("counter-rates-latches", "1"), 10)
+
+	senders = collectMetricGroup(t, oracleLatches, sqlmock.NewRows(columns).
+		AddRow("1", "shared pool", 1100, 1, 0, 0, 0, 0, 0.0))
+	var gets float64
+	for _, sender := range senders {
+		if sender.metric.name == "latch.getsPerSecond" {
+			gets = sender.metric.value.(float64)
+		}
+	}
+	if math.Abs(gets-100) > 0.1 {
+		t.Errorf("Expected 100 gets per second from the counters after the reset, got %v", gets)
+	}
+}
+
+func TestTopCounterEntries(t *testing.T) {
+	entries := []*counterEntry{
+		{metadata: map[string]string{"name": "TX"}, rates: map[string]float64{"WAITS": 5, "REQUESTS": 10}},
+		{metadata: map[string]string{"name": "TM"}, rates: map[string]float64{"WAITS": 5, "REQUESTS": 90}},
+		{metadata: map[string]string{"name": "CF"}, rates: map[string]float64{"WAITS": 1, "REQUESTS": 500}},
+	}
+
+	top := topCounterEntries(entries, []string{"WAITS", "REQUESTS"}, 2)
+	if len(top) != 2 || top[0].metadata["name"] != "TM" || top[1].metadata["name"] != "TX" {
+		t.Errorf("Unexpected top entries %v, %v", top[0].metadata, top[1].metadata)
+	}
+
+	if all := topCounterEntries(entries, []string{"WAITS"}, 0); len(all) != 3 {
+		t.Errorf("Expected every entry, got %d", len(all))
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+// customQueryParameter is a named bind parameter of a custom query. It is either
+// a literal value or read from the environment variable in env
+type customQueryParameter struct {
+	Value interface{} `yaml:"value"`
+	Env   string      `yaml:"env"`
+}
+
+func (p *customQueryParameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var parameter struct {
+		Value interface{} `yaml:"value"`
+		Env   string      `yaml:"env"`
+	}
+	if err := unmarshal(&parameter); err == nil {
+		p.Value, p.Env = parameter.Value, parameter.Env
+		return nil
+	}
+
+	return unmarshal(&p.Value)
+}
+
+// customDuration is a duration written as a Go duration string, e.g. 5m or 30s
+type customDuration time.Duration
+
+func (d *customDuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+
+	*d = customDuration(duration)
+	return nil
+}
+
+// customQueryBindArgs returns the parameters of a custom query as named bind
+// arguments, sorted by name
+func customQueryBindArgs(cfg customMetricsConfig) ([]interface{}, error) {
+	names := make([]string, 0, len(cfg.Parameters))
+	for name := range cfg.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bindArgs := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		parameter := cfg.Parameters[name]
+		value := parameter.Value
+		if parameter.Env != "" {
+			envValue, ok := os.LookupEnv(parameter.Env)
+			if !ok {
+				return nil, fmt.Errorf("environment variable %s of parameter %s is not set", parameter.Env, name)
+			}
+			value = envValue
+		}
+		bindArgs = append(bindArgs, sql.Named(name, value))
+	}
+
+	return bindArgs, nil
+}
+
+// customQueryKey identifies a custom query in the state store by its sample name,
+// query and parameters, so the same query bound to other values runs on its own interval
+func customQueryKey(cfg customMetricsConfig) string {
+	names := make([]string, 0, len(cfg.Parameters))
+	for name := range cfg.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := []string{cfg.SampleName, cfg.Query}
+	for _, name := range names {
+		parameter := cfg.Parameters[name]
+		if parameter.Env != "" {
+			parts = append(parts, fmt.Sprintf("%s=env:%s", name, parameter.Env))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s=%v", name, parameter.Value))
+		}
+	}
+	return stateKey("custom-query", strings.Join(parts, "|"))
+}
+
+// customQueryDue reports whether a custom query has to run in this execution. Queries
+// with an interval run again once the interval has elapsed since their last successful
+// run, which is kept in the state store by markCustomQueryRun
+func customQueryDue(cfg customMetricsConfig) bool {
+	if cfg.Interval <= 0 {
+		return true
+	}
+
+	var lastRun int64
+	if _, err := stateStore.Get(customQueryKey(cfg), &lastRun); err == nil && time.Since(time.Unix(lastRun, 0)) < time.Duration(cfg.Interval) {
+		log.Debug("Custom query %s ran less than %s ago, skipping", formatQueryForLogging(cfg.Query), time.Duration(cfg.Interval))
+		return false
+	}
+	return true
+}
+
+// markCustomQueryRun records a successful run of a custom query with an interval,
+// so a failed run is retried in the next execution
+func markCustomQueryRun(cfg customMetricsConfig) {
+	if cfg.Interval <= 0 {
+		return
+	}
+	stateStore.Set(customQueryKey(cfg), time.Now().Unix())
+}
+
+// customQueryVersionMatches reports whether version is within the min_version and
+// max_version of a custom query. Guards are compared on as many components as they
+// have, so a max_version of 12 includes every 12.x release
+func customQueryVersionMatches(cfg customMetricsConfig, version string) bool {
+	if cfg.MinVersion != "" && compareVersionPrefix(version, cfg.MinVersion) < 0 {
+		return false
+	}
+	if cfg.MaxVersion != "" && compareVersionPrefix(version, cfg.MaxVersion) > 0 {
+		return false
+	}
+	return true
+}
+
+// compareVersionPrefix compares the leading components of version with guard,
+// returning -1, 0 or 1
+func compareVersionPrefix(version string, guard string) int {
+	versionParts := strings.Split(strings.TrimSpace(version), ".")
+	for i, guardPart := range strings.Split(strings.TrimSpace(guard), ".") {
+		var versionNumber int
+		if i < len(versionParts) {
+			versionNumber, _ = strconv.Atoi(versionParts[i])
+		}
+		guardNumber, _ := strconv.Atoi(guardPart)
+
+		if versionNumber < guardNumber {
+			return -1
+		}
+		if versionNumber > guardNumber {
+			return 1
+		}
+	}
+	return 0
+}
+
+// databaseVersion returns the version of the instance the integration is connected to
+func databaseVersion(db database.DBWrapper) (string, error) {
+	const versionQuery = `SELECT VERSION FROM v$instance`
+
+	var version string
+	if err := db.QueryRow(versionQuery).Scan(&version); err != nil {
+		return "", fmt.Errorf("querying database version: %w", err)
+	}
+	return version, nil
+}
+
+const customColumnCastNumber = "number"
+
+// customColumn holds the transformations applied to a column of a custom query
+// before it is reported
+type customColumn struct {
+	Rename    string  `yaml:"rename"`
+	Prefix    string  `yaml:"prefix"`
+	Scale     float64 `yaml:"scale"`
+	Cast      string  `yaml:"cast"`
+	Attribute bool    `yaml:"attribute"`
+}
+
+// customColumnsByName indexes the column transformations of a custom query by
+// upper case column name, since Oracle upper cases unquoted column aliases
+func customColumnsByName(columns map[string]customColumn) map[string]customColumn {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	byName := make(map[string]customColumn, len(columns))
+	for name, column := range columns {
+		byName[strings.ToUpper(name)] = column
+	}
+	return byName
+}
+
+// transform returns the reported name and value of a sanitized column value.
+// Attribute columns are converted to strings
+func (c customColumn) transform(name string, value interface{}) (string, interface{}, error) {
+	if c.Rename != "" {
+		name = c.Rename
+	}
+	name = c.Prefix + name
+
+	if c.Cast == customColumnCastNumber {
+		if s, ok := value.(string); ok {
+			number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return name, nil, fmt.Errorf("casting column %s to a number: %w", name, err)
+			}
+			value = number
+		}
+	}
+
+	if c.Scale != 0 {
+		if number, ok := toFloat64(value); ok {
+			value = number * c.Scale
+		}
+	}
+
+	if c.Attribute {
+		if _, ok := value.(string); !ok {
+			value = fmt.Sprintf("%v", value)
+		}
+	}
+
+	return name, value, nil
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/newrelic/nri-oracledb/src/database"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_customMetricsConfig_Unmarshal(t *testing.T) {
+	contents := `
+queries:
+  - query: SELECT owner, bytes FROM dba_segments WHERE owner = :owner AND bytes > :min_bytes
+    parameters:
+      owner: HR
+      min_bytes:
+        env: MIN_BYTES
+    interval: 10m
+    timeout: 30s
+    max_rows: 100
+    min_version: 12.1
+    max_version: "19"
+`
+	var customYAML customMetricsYAML
+	if err := yaml.Unmarshal([]byte(contents), &customYAML); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	cfg := customYAML.Queries[0]
+	expectedParameters := map[string]customQueryParameter{
+		"owner":     {Value: "HR"},
+		"min_bytes": {Env: "MIN_BYTES"},
+	}
+	if !reflect.DeepEqual(cfg.Parameters, expectedParameters) {
+		t.Errorf("Expected parameters %+v, got %+v", expectedParameters, cfg.Parameters)
+	}
+	if time.Duration(cfg.Interval) != 10*time.Minute || time.Duration(cfg.Timeout) != 30*time.Second {
+		t.Errorf("Unexpected interval %s or timeout %s", time.Duration(cfg.Interval), time.Duration(cfg.Timeout))
+	}
+	if cfg.MaxRows != 100 || cfg.MinVersion != "12.1" || cfg.MaxVersion != "19" {
+		t.Errorf("Unexpected max_rows %d, min_version %s or max_version %s", cfg.MaxRows, cfg.MinVersion, cfg.MaxVersion)
+	}
+}
+
+func Test_customQueryBindArgs(t *testing.T) {
+	cfg := customMetricsConfig{
+		Parameters: map[string]customQueryParameter{
+			"owner":     {Value: "HR"},
+			"min_bytes": {Env: "TEST_MIN_BYTES"},
+		},
+	}
+
+	t.Setenv("TEST_MIN_BYTES", "1024")
+	bindArgs, err := customQueryBindArgs(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expected := []interface{}{sql.Named("min_bytes", "1024"), sql.Named("owner", "HR")}
+	if !reflect.DeepEqual(bindArgs, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, bindArgs)
+	}
+
+	cfg.Parameters["missing"] = customQueryParameter{Env: "TEST_MISSING_VARIABLE"}
+	if _, err := customQueryBindArgs(cfg); err == nil {
+		t.Error("Did not return expected error for an unset environment variable")
+	}
+}
+
+func Test_customQueryDue(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	cfg := customMetricsConfig{Query: "SELECT 1 FROM dual"}
+	markCustomQueryRun(cfg)
+	if !customQueryDue(cfg) {
+		t.Error("Expected queries without interval to run every time")
+	}
+
+	cfg.Interval = customDuration(time.Hour)
+	if !customQueryDue(cfg) || !customQueryDue(cfg) {
+		t.Error("Expected a query with interval to be due until it runs successfully")
+	}
+	markCustomQueryRun(cfg)
+	if customQueryDue(cfg) {
+		t.Error("Expected a query run less than an interval ago not to be due")
+	}
+
+	stateStore.Set(customQueryKey(cfg), time.Now().Add(-2*time.Hour).Unix())
+	if !customQueryDue(cfg) {
+		t.Error("Expected a query run more than an interval ago to be due")
+	}
+}
+
+func Test_customQueryDue_Parameters(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	users := customMetricsConfig{
+		Query:      "SELECT COUNT(*) FROM dba_segments WHERE tablespace_name = :tablespace",
+		Interval:   customDuration(time.Hour),
+		Parameters: map[string]customQueryParameter{"tablespace": {Value: "USERS"}},
+	}
+	system := users
+	system.Parameters = map[string]customQueryParameter{"tablespace": {Value: "SYSTEM"}}
+
+	markCustomQueryRun(users)
+	if customQueryDue(users) {
+		t.Error("Expected the query bound to USERS not to be due")
+	}
+	if !customQueryDue(system) {
+		t.Error("Expected the query bound to SYSTEM to be due")
+	}
+}
+
+func Test_customQueryVersionMatches(t *testing.T) {
+	testCases := []struct {
+		version  string
+		min      string
+		max      string
+		expected bool
+	}{
+		{"19.0.0.0.0", "", "", true},
+		{"19.0.0.0.0", "12.1", "", true},
+		{"11.2.0.4.0", "12.1", "", false},
+		{"12.1.0.2.0", "12.1", "", true},
+		{"12.2.0.1.0", "", "12", true},
+		{"18.0.0.0.0", "", "12", false},
+		{"12.2.0.1.0", "12.1", "12.2", true},
+		{"12.1.0.2.0", "12.2", "19", false},
+	}
+
+	for _, tc := range testCases {
+		cfg := customMetricsConfig{MinVersion: tc.min, MaxVersion: tc.max}
+		if got := customQueryVersionMatches(cfg, tc.version); got != tc.expected {
+			t.Errorf("Version %s within [%s, %s]: expected %t, got %t", tc.version, tc.min, tc.max, tc.expected, got)
+		}
+	}
+}
+
+func TestCollectCustomConfig_ParametersAndMaxRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("SELECT.*FROM v\\$instance").WillReturnRows(
+		sqlmock.NewRows([]string{"INSTANCE_NUMBER"}).AddRow("1"),
+	)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT.*FROM dba_segments.*").
+		WithArgs(sql.Named("owner", "HR")).
+		WillReturnRows(sqlmock.NewRows([]string{"SEGMENT_NAME", "BYTES"}).
+			AddRow("A", 1).
+			AddRow("B", 2).
+			AddRow("C", 3))
+	mock.ExpectRollback()
+
+	dbWrapper := database.NewDBWrapper(sqlx.NewDb(db, "sqlmock"))
+	metricChan := make(chan newrelicMetricSender, 1)
+	CollectCustomConfig(dbWrapper, metricChan, customMetricsConfig{
+		Query:      "SELECT segment_name, bytes FROM dba_segments WHERE owner = :owner",
+		Parameters: map[string]customQueryParameter{"owner": {Value: "HR"}},
+		Timeout:    customDuration(time.Minute),
+		MaxRows:    2,
+	})
+	close(metricChan)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	sender := <-metricChan
+	if len(sender.customMetrics) != 2 {
+		t.Errorf("Expected 2 rows, got %d", len(sender.customMetrics))
+	}
+}
+
+func TestCollectCustomConfig_FailedRunNotRecorded(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("SELECT.*FROM v\\$instance").WillReturnRows(
+		sqlmock.NewRows([]string{"INSTANCE_NUMBER"}).AddRow("1"),
+	)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT.*FROM dba_segments.*").WillReturnError(errors.New("ORA-01013: user requested cancel of current operation"))
+	mock.ExpectRollback()
+
+	cfg := customMetricsConfig{
+		Query:    "SELECT segment_name, bytes FROM dba_segments",
+		Interval: customDuration(time.Hour),
+	}
+	dbWrapper := database.NewDBWrapper(sqlx.NewDb(db, "sqlmock"))
+	metricChan := make(chan newrelicMetricSender, 1)
+	CollectCustomConfig(dbWrapper, metricChan, cfg)
+	close(metricChan)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if !customQueryDue(cfg) {
+		t.Error("Expected a failed query to be due in the next execution")
+	}
+}
+
+func Test_customColumn_transform(t *testing.T) {
+	testCases := []struct {
+		name          string
+		column        customColumn
+		value         interface{}
+		expectedName  string
+		expectedValue interface{}
+		expectError   bool
+	}{
+		{"rename and prefix", customColumn{Rename: "waitTime", Prefix: "db.custom."}, int64(3), "db.custom.waitTime", int64(3), false},
+		{"scale centiseconds to milliseconds", customColumn{Scale: 10}, int64(12), "TIME_WAITED", float64(120), false},
+		{"cast string to number", customColumn{Cast: "number"}, " 1.5 ", "TIME_WAITED", 1.5, false},
+		{"cast and scale", customColumn{Cast: "number", Scale: 0.001}, "2000", "TIME_WAITED", float64(2), false},
+		{"cast failure", customColumn{Cast: "number"}, "n/a", "TIME_WAITED", nil, true},
+		{"number as attribute", customColumn{Attribute: true}, int64(42), "TIME_WAITED", "42", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, value, err := tc.column.transform("TIME_WAITED", tc.value)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if tc.expectError {
+				return
+			}
+			if name != tc.expectedName || !reflect.DeepEqual(value, tc.expectedValue) {
+				t.Errorf("Expected %s=%v (%T), got %s=%v (%T)", tc.expectedName, tc.expectedValue, tc.expectedValue, name, value, value)
+			}
+		})
+	}
+}
+
+func TestPopulateMetrics_CustomColumns(t *testing.T) {
+	args = argumentList{
+		Hostname:    "testhost",
+		Port:        "1234",
+		ServiceName: "testServiceName",
+	}
+	defer func() { args = argumentList{} }()
+
+	i, _ := integration.New("oracletest", "0.0.1")
+	metricChan := make(chan newrelicMetricSender, 1)
+	metricChan <- newrelicMetricSender{
+		isCustom: true,
+		metadata: map[string]string{
+			"instanceID": "1",
+			"sampleName": defaultCustomSampleType,
+		},
+		customColumns: customColumnsByName(map[string]customColumn{
+			"time_waited": {Rename: "timeWaitedMs", Scale: 10},
+			"class":       {Attribute: true},
+			"last_wait":   {Prefix: "wait."},
+		}),
+		customMetrics: []map[string]interface{}{
+			{
+				"TIME_WAITED": int64(5),
+				"CLASS":       int64(7),
+				"LAST_WAIT":   time.Unix(1700000000, 0),
+			},
+		},
+	}
+	close(metricChan)
+
+	populateMetrics(metricChan, i, map[string]string{"1": "one"})
+
+	metrics := i.Entities[0].Metrics[0].Metrics
+	expected := map[string]interface{}{
+		"timeWaitedMs":   float64(50),
+		"CLASS":          "7",
+		"wait.LAST_WAIT": float64(1700000000),
+	}
+	for name, value := range expected {
+		if !reflect.DeepEqual(metrics[name], value) {
+			t.Errorf("Expected %s=%v (%T), got %v (%T)", name, value, value, metrics[name], metrics[name])
+		}
+	}
+	if _, ok := metrics["TIME_WAITED"]; ok {
+		t.Error("Expected the renamed column not to be reported under its original name")
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -40,6 +41,35 @@ func (d *DBWrapper) Queryx(query string, args ...interface{}) (*RowsxWrapper, er
 	return &RowsxWrapper{Rows: rows}, err
 }
 
+// BeginReadOnly starts a read-only transaction, in which the database rejects any
+// statement that would modify data
+func (d *DBWrapper) BeginReadOnly(ctx context.Context) (*TxWrapper, error) {
+	tx, err := d.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	return &TxWrapper{tx: tx}, err
+}
+
+type TxWrapper struct {
+	tx *sqlx.Tx
+}
+
+// QueryxContext runs a query in the transaction
+func (t *TxWrapper) QueryxContext(ctx context.Context, query string, args ...interface{}) (*RowsxWrapper, error) {
+	rows, err := t.tx.QueryxContext(ctx, query, args...)
+	return &RowsxWrapper{Rows: rows}, err
+}
+
+// Rollback ends the transaction
+func (t *TxWrapper) Rollback() {
+	if t.tx == nil {
+		return
+	}
+	// The transaction is already rolled back when its context is cancelled
+	err := t.tx.Rollback()
+	if err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Error("Failed to roll back transaction: %s", err)
+	}
+}
+
 type RowsWrapper struct {
 	count int
 	*sql.Rows
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	describeFormatText     = "text"
+	describeFormatMarkdown = "markdown"
+	describeFormatCSV      = "csv"
+)
+
+// metricGroupEntityType returns the entity type a registered metric group reports on
+func metricGroupEntityType(group oracleMetricGroup) string {
+	if group.entityType != "" {
+		return group.entityType
+	}
+	for _, tablespaceGroup := range tablespaceMetricGroups {
+		if tablespaceGroup.name == group.name {
+			return "ora-tablespace"
+		}
+	}
+	return "ora-instance"
+}
+
+// findMetricGroup returns the registered metric group matching name case-insensitively
+func findMetricGroup(name string) (oracleMetricGroup, bool) {
+	for _, group := range registeredMetricGroups() {
+		if strings.EqualFold(group.name, name) {
+			return group, true
+		}
+	}
+	return oracleMetricGroup{}, false
+}
+
+// listMetricGroups writes every registered metric group, sorted by name, in the given format.
+// A group registered more than once is only listed once
+func listMetricGroups(w io.Writer, format string) error {
+	groups := registeredMetricGroups()
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].name < groups[j].name
+	})
+
+	unique := groups[:0]
+	for _, group := range groups {
+		if len(unique) > 0 && unique[len(unique)-1].name == group.name {
+			continue
+		}
+		unique = append(unique, group)
+	}
+
+	return writeMetricGroups(w, format, unique)
+}
+
+// describeMetricGroup writes a single metric group in the given format
+func describeMetricGroup(w io.Writer, format string, name string) error {
+	group, ok := findMetricGroup(name)
+	if !ok {
+		return fmt.Errorf("unknown metric group %q", name)
+	}
+
+	return writeMetricGroups(w, format, []oracleMetricGroup{group})
+}
+
+func writeMetricGroups(w io.Writer, format string, groups []oracleMetricGroup) error {
+	switch strings.ToLower(format) {
+	case describeFormatText, "":
+		return writeMetricGroupsText(w, groups)
+	case describeFormatMarkdown:
+		return writeMetricGroupsMarkdown(w, groups)
+	case describeFormatCSV:
+		return writeMetricGroupsCSV(w, groups)
+	default:
+		return fmt.Errorf("unknown metric groups format %q, expected one of %s, %s or %s", format, describeFormatText, describeFormatMarkdown, describeFormatCSV)
+	}
+}
+
+func writeMetricGroupsText(w io.Writer, groups []oracleMetricGroup) error {
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", group.name)
+		fmt.Fprintf(w, "  Entity type: %s\n", metricGroupEntityType(group))
+		if modes := metricGroupCollectionModes(group); len(modes) > 0 {
+			fmt.Fprintf(w, "  Collection: %s\n", strings.Join(modes, ", "))
+		}
+		fmt.Fprintf(w, "  Query:\n")
+		for _, line := range metricGroupQueryLines(group) {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+		fmt.Fprintf(w, "  Metrics:\n")
+		for _, metric := range group.metrics {
+			fmt.Fprintf(w, "    %s (%s, %s)\n", metric.name, metric.metricType, metricCollectionMode(metric))
+		}
+		if len(group.events) > 0 {
+			fmt.Fprintf(w, "  Events:\n")
+			for _, summary := range group.events {
+				fmt.Fprintf(w, "    %s\n", summary)
+			}
+		}
+	}
+	return nil
+}
+
+func writeMetricGroupsMarkdown(w io.Writer, groups []oracleMetricGroup) error {
+	fmt.Fprintln(w, "| Metric Group | Entity Type | SQL Query | Affected Metrics |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- |")
+	for _, group := range groups {
+		metrics := make([]string, 0, len(group.metrics))
+		for _, metric := range group.metrics {
+			if metric.defaultMetric {
+				metrics = append(metrics, metric.name)
+			} else {
+				metrics = append(metrics, metric.name+" (extended)")
+			}
+		}
+		for _, summary := range group.events {
+			metrics = append(metrics, "Event: "+summary)
+		}
+
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n",
+			markdownGroupName(group),
+			metricGroupEntityType(group),
+			markdownCell(metricGroupQueryLines(group)),
+			markdownCell(metrics),
+		)
+	}
+	return nil
+}
+
+func writeMetricGroupsCSV(w io.Writer, groups []oracleMetricGroup) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"metric_group", "entity_type", "metric_name", "metric_type", "metric_enabled", "source_identifier"}); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		for _, metric := range group.metrics {
+			err := writer.Write([]string{
+				group.name,
+				metricGroupEntityType(group),
+				metric.name,
+				metric.metricType.String(),
+				fmt.Sprintf("%t", metric.defaultMetric),
+				metric.identifier,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// metricGroupQueryLines returns the non-empty lines of the group query, trimmed,
+// as it would be built with no tablespace whitelist
+func metricGroupQueryLines(group oracleMetricGroup) []string {
+	var lines []string
+	for _, line := range strings.Split(group.sqlQuery(group.metrics), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// metricGroupCollectionModes returns how a group is collected when it differs from
+// every run by default: opt-in, slow or limited to older releases
+func metricGroupCollectionModes(group oracleMetricGroup) []string {
+	var modes []string
+	if group.optIn {
+		modes = append(modes, "opt-in")
+	}
+	if group.slow {
+		modes = append(modes, "slow")
+	}
+	if group.maxVersion != "" {
+		modes = append(modes, "up to "+group.maxVersion)
+	}
+	return modes
+}
+
+// markdownGroupName returns the group name cell, followed by its collection modes
+func markdownGroupName(group oracleMetricGroup) string {
+	name := "`" + group.name + "`"
+	for _, mode := range metricGroupCollectionModes(group) {
+		name += " (" + mode + ")"
+	}
+	return name
+}
+
+func metricCollectionMode(metric *oracleMetric) string {
+	if metric.defaultMetric {
+		return "default"
+	}
+	return "extended"
+}
+
+func markdownCell(lines []string) string {
+	escaped := make([]string, 0, len(lines))
+	for _, line := range lines {
+		escaped = append(escaped, strings.ReplaceAll(line, "|", `\|`))
+	}
+	return strings.Join(escaped, "<br/>")
+}
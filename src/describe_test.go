@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_describeMetricGroup(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: describeFormatText,
+			expected: `pga_metrics
+  Entity type: ora-instance
+  Query:
+    SELECT INST_ID, NAME, VALUE FROM gv$pgastat WHERE NAME IN ('total PGA inuse','total PGA allocated','total freeable PGA memory','global memory bound')
+  Metrics:
+    memory.pgaInUseInBytes (gauge, extended)
+    memory.pgaAllocatedInBytes (gauge, extended)
+    memory.pgaFreeableInBytes (gauge, extended)
+    memory.pgaMaxSizeInBytes (gauge, default)
+`,
+		},
+		{
+			format: describeFormatMarkdown,
+			expected: "| Metric Group | Entity Type | SQL Query | Affected Metrics |\n" +
+				"| --- | --- | --- | --- |\n" +
+				"| `pga_metrics` | ora-instance | SELECT INST_ID, NAME, VALUE FROM gv$pgastat WHERE NAME IN ('total PGA inuse','total PGA allocated','total freeable PGA memory','global memory bound') | " +
+				"memory.pgaInUseInBytes (extended)<br/>memory.pgaAllocatedInBytes (extended)<br/>memory.pgaFreeableInBytes (extended)<br/>memory.pgaMaxSizeInBytes |\n",
+		},
+		{
+			format: describeFormatCSV,
+			expected: `metric_group,entity_type,metric_name,metric_type,metric_enabled,source_identifier
+pga_metrics,ora-instance,memory.pgaInUseInBytes,gauge,false,total PGA inuse
+pga_metrics,ora-instance,memory.pgaAllocatedInBytes,gauge,false,total PGA allocated
+pga_metrics,ora-instance,memory.pgaFreeableInBytes,gauge,false,total freeable PGA memory
+pga_metrics,ora-instance,memory.pgaMaxSizeInBytes,gauge,true,global memory bound
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := describeMetricGroup(&out, tc.format, "PGA_METRICS"); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tc.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tc.expected, out.String())
+			}
+		})
+	}
+}
+
+func Test_describeMetricGroup_Errors(t *testing.T) {
+	var out bytes.Buffer
+	if err := describeMetricGroup(&out, describeFormatText, "unknown_group"); err == nil {
+		t.Error("Expected error for unknown metric group")
+	}
+
+	if err := describeMetricGroup(&out, "yaml", "sga"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func Test_listMetricGroups(t *testing.T) {
+	var out bytes.Buffer
+	if err := listMetricGroups(&out, describeFormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, group := range registeredMetricGroups() {
+		if !strings.Contains(out.String(), "| "+markdownGroupName(group)+" | "+metricGroupEntityType(group)+" |") {
+			t.Errorf("Metric group %s missing from the list", group.name)
+		}
+	}
+}
+
+func Test_markdownGroupName(t *testing.T) {
+	testCases := []struct {
+		group    oracleMetricGroup
+		expected string
+	}{
+		{oracleMetricGroup{name: "sga"}, "`sga`"},
+		{oracleMetricGroup{name: "schema_health", slow: true}, "`schema_health` (slow)"},
+		{oracleMetricGroup{name: "latches", optIn: true}, "`latches` (opt-in)"},
+		{oracleMetricGroup{name: "segments", optIn: true, slow: true}, "`segments` (opt-in) (slow)"},
+		{oracleMetricGroup{name: "legacy_jobs", maxVersion: "11"}, "`legacy_jobs` (up to 11)"},
+	}
+
+	for _, tc := range testCases {
+		if got := markdownGroupName(tc.group); got != tc.expected {
+			t.Errorf("Expected %s, got %s", tc.expected, got)
+		}
+	}
+}
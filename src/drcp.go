@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+)
+
+const (
+	connectionPoolSample  = "OracleConnectionPoolSample"
+	connectionClassSample = "OracleConnectionClassSample"
+	// connectionPoolEnabledQuery turns the DRCP groups off when no pool is started
+	connectionPoolEnabledQuery = `SELECT COUNT(*) FROM dba_cpool_info WHERE STATUS = 'ACTIVE'`
+)
+
+// oracleConnectionPools reports the DRCP pools of the instance the integration is
+// connected to, the only one covered by v$cpool_stats
+var oracleConnectionPools = oracleMetricGroup{
+	name:         "connection_pools",
+	enabledQuery: connectionPoolEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			SYS_CONTEXT('USERENV', 'INSTANCE') AS INST_ID,
+			POOL_NAME,
+			NUM_OPEN_SERVERS,
+			NUM_BUSY_SERVERS,
+			NUM_OPEN_SERVERS - NUM_BUSY_SERVERS AS NUM_FREE_SERVERS,
+			NUM_AUTH_SERVERS,
+			NUM_REQUESTS,
+			NUM_HITS,
+			NUM_MISSES,
+			NUM_WAITS,
+			CLIENT_REQ_TIMEOUTS,
+			NUM_AUTHENTICATIONS,
+			NUM_PURGED,
+			HISTORIC_MAX
+		FROM v$cpool_stats`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "drcp.pool.openServers",
+			identifier:    "NUM_OPEN_SERVERS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.busyServers",
+			identifier:    "NUM_BUSY_SERVERS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.freeServers",
+			identifier:    "NUM_FREE_SERVERS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.authenticationServers",
+			identifier:    "NUM_AUTH_SERVERS",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "drcp.pool.requestsPerSecond",
+			identifier:    "NUM_REQUESTS",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.hitsPerSecond",
+			identifier:    "NUM_HITS",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.missesPerSecond",
+			identifier:    "NUM_MISSES",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.waitsPerSecond",
+			identifier:    "NUM_WAITS",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.requestTimeoutsPerSecond",
+			identifier:    "CLIENT_REQ_TIMEOUTS",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.authenticationsPerSecond",
+			identifier:    "NUM_AUTHENTICATIONS",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "drcp.pool.purgedPerSecond",
+			identifier:    "NUM_PURGED",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.pool.historicMaxServers",
+			identifier:    "HISTORIC_MAX",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			"instanceID":                     getInstanceIDString(row["INST_ID"]),
+			sampleMetadataKey:                connectionPoolSample,
+			dimensionMetadataPrefix + "pool": stringValue(row["POOL_NAME"]),
+		}
+	}),
+}
+
+var oracleConnectionClasses = oracleMetricGroup{
+	name:         "connection_classes",
+	enabledQuery: connectionPoolEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			SYS_CONTEXT('USERENV', 'INSTANCE') AS INST_ID,
+			CCLASS_NAME,
+			NUM_REQUESTS,
+			NUM_HITS,
+			NUM_MISSES,
+			NUM_WAITS,
+			CLIENT_REQ_TIMEOUTS,
+			NUM_AUTHENTICATIONS
+		FROM v$cpool_cc_stats`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "drcp.connectionClass.requestsPerSecond",
+			identifier:    "NUM_REQUESTS",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.connectionClass.hitsPerSecond",
+			identifier:    "NUM_HITS",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.connectionClass.missesPerSecond",
+			identifier:    "NUM_MISSES",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.connectionClass.waitsPerSecond",
+			identifier:    "NUM_WAITS",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "drcp.connectionClass.requestTimeoutsPerSecond",
+			identifier:    "CLIENT_REQ_TIMEOUTS",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "drcp.connectionClass.authenticationsPerSecond",
+			identifier:    "NUM_AUTHENTICATIONS",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			"instanceID":      getInstanceIDString(row["INST_ID"]),
+			sampleMetadataKey: connectionClassSample,
+			dimensionMetadataPrefix + "connectionClass": stringValue(row["CCLASS_NAME"]),
+		}
+	}),
+}
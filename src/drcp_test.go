@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+func TestConnectionPoolsNotStarted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM dba_cpool_info`).
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+
+	var wg sync.WaitGroup
+	metricChan := make(chan newrelicMetricSender, 10)
+	wg.Add(1)
+	oracleConnectionPools.Collect(database.NewDBWrapper(sqlx.NewDb(db, "sqlmock")), &wg, metricChan)
+	close(metricChan)
+
+	if len(metricChan) != 0 {
+		t.Errorf("Expected no metrics without a started pool, got %d", len(metricChan))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnectionPools(t *testing.T) {
+	columns := []string{"INST_ID", "POOL_NAME", "NUM_OPEN_SERVERS", "NUM_BUSY_SERVERS", "NUM_FREE_SERVERS", "NUM_AUTH_SERVERS", "NUM_REQUESTS", "NUM_HITS", "NUM_MISSES", "NUM_WAITS", "CLIENT_REQ_TIMEOUTS", "NUM_AUTHENTICATIONS", "NUM_PURGED", "HISTORIC_MAX"}
+	senders := collectMetricGroup(t, oracleConnectionPools, sqlmock.NewRows(columns).
+		AddRow("1", "SYS_DEFAULT_CONNECTION_POOL", 40, 25, 15, 1, 120000, 118000, 2000, 35, 0, 2000, 4, 40))
+
+	values := make(map[string]*newrelicMetric)
+	for _, sender := range senders {
+		if sender.metadata["instanceID"] != "1" || sender.metadata[sampleMetadataKey] != connectionPoolSample || sender.metadata[dimensionMetadataPrefix+"pool"] != "SYS_DEFAULT_CONNECTION_POOL" {
+			t.Errorf("Unexpected metadata %v", sender.metadata)
+		}
+		values[sender.metric.name] = sender.metric
+	}
+
+	if values["drcp.pool.freeServers"].value != int64(15) || values["drcp.pool.historicMaxServers"].value != int64(40) {
+		t.Errorf("Unexpected server metrics %v", values)
+	}
+	if values["drcp.pool.hitsPerSecond"].metricType != metric.RATE {
+		t.Errorf("Expected hits to be reported as a rate")
+	}
+	if _, ok := values["drcp.pool.authenticationServers"]; ok {
+		t.Errorf("Expected extended metrics to be left out")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+// collectMetricGroup runs the generator of group on rows and returns what it sent
+func collectMetricGroup(t *testing.T, group oracleMetricGroup, rows *sqlmock.Rows) []newrelicMetricSender {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(".*").WillReturnRows(rows)
+
+	dbWrapper := database.NewDBWrapper(sqlx.NewDb(db, "sqlmock"))
+	result, err := dbWrapper.Query(group.sqlQuery(group.metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metricChan := make(chan newrelicMetricSender, 100)
+	if err := group.metricsGenerator(result, group.metrics, metricChan); err != nil {
+		t.Fatal(err)
+	}
+	close(metricChan)
+
+	var senders []newrelicMetricSender
+	for sender := range metricChan {
+		senders = append(senders, sender)
+	}
+	return senders
+}
+
+// rewindCounters moves the counters kept under key seconds back, as if they were
+// collected by an earlier run
+func rewindCounters(t *testing.T, key string, seconds int64) {
+	var snapshot counterSnapshot
+	if _, err := stateStore.Get(key, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	snapshot.Time -= seconds * 1000
+	stateStore.Set(key, snapshot)
+}
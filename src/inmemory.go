@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+)
+
+const (
+	inMemoryPoolSample    = "OracleInMemoryPoolSample"
+	inMemorySegmentSample = "OracleInMemorySegmentSample"
+	// inMemoryEnabledQuery turns the In-Memory groups off when no column store is
+	// configured, and on releases without one
+	inMemoryEnabledQuery = `SELECT COUNT(*) FROM gv$parameter WHERE NAME = 'inmemory_size' AND VALUE <> '0'`
+)
+
+var oracleInMemory = oracleMetricGroup{
+	name:         "inmemory",
+	enabledQuery: inMemoryEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			i.INST_ID,
+			NVL(s.SEGMENTS, 0) AS SEGMENTS,
+			NVL(s.SEGMENTS_NOT_POPULATED, 0) AS SEGMENTS_NOT_POPULATED,
+			NVL(s.BYTES_NOT_POPULATED, 0) AS BYTES_NOT_POPULATED,
+			s.COMPRESSION_RATIO
+		FROM gv$instance i
+		LEFT JOIN (
+			SELECT
+				INST_ID,
+				COUNT(*) AS SEGMENTS,
+				SUM(CASE WHEN POPULATE_STATUS <> 'COMPLETED' OR BYTES_NOT_POPULATED > 0 THEN 1 ELSE 0 END) AS SEGMENTS_NOT_POPULATED,
+				SUM(BYTES_NOT_POPULATED) AS BYTES_NOT_POPULATED,
+				SUM(BYTES - BYTES_NOT_POPULATED) / NULLIF(SUM(INMEMORY_SIZE), 0) AS COMPRESSION_RATIO
+			FROM gv$im_segments
+			GROUP BY INST_ID
+		) s ON s.INST_ID = i.INST_ID`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "inmemory.segments",
+			identifier:    "SEGMENTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.segmentsNotFullyPopulated",
+			identifier:    "SEGMENTS_NOT_POPULATED",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.bytesNotPopulated",
+			identifier:    "BYTES_NOT_POPULATED",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.compressionRatio",
+			identifier:    "COMPRESSION_RATIO",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{"instanceID": getInstanceIDString(row["INST_ID"])}
+	}),
+}
+
+var oracleInMemoryArea = oracleMetricGroup{
+	name:         "inmemory_area",
+	enabledQuery: inMemoryEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			INST_ID,
+			POOL,
+			SUM(ALLOC_BYTES) AS ALLOC_BYTES,
+			SUM(USED_BYTES) AS USED_BYTES,
+			SUM(USED_BYTES) * 100 / NULLIF(SUM(ALLOC_BYTES), 0) AS USED_PERCENT
+		FROM gv$inmemory_area
+		GROUP BY INST_ID, POOL`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "inmemory.pool.allocatedInBytes",
+			identifier:    "ALLOC_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.pool.populatedInBytes",
+			identifier:    "USED_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.pool.populatedPercentage",
+			identifier:    "USED_PERCENT",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			"instanceID":                     getInstanceIDString(row["INST_ID"]),
+			sampleMetadataKey:                inMemoryPoolSample,
+			dimensionMetadataPrefix + "pool": stringValue(row["POOL"]),
+		}
+	}),
+}
+
+var oracleInMemorySegments = oracleMetricGroup{
+	name:         "inmemory_segments",
+	enabledQuery: inMemoryEnabledQuery,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Only the SEGMENTS_TOP_N segments of every instance with the most bytes left to
+		// populate are reported, as there can be one sample per partition
+		query := `
+		SELECT
+			INST_ID,
+			OWNER,
+			SEGMENT_NAME,
+			PARTITION_NAME,
+			SEGMENT_TYPE,
+			INMEMORY_SIZE,
+			BYTES,
+			BYTES_NOT_POPULATED,
+			(BYTES - BYTES_NOT_POPULATED) / NULLIF(INMEMORY_SIZE, 0) AS COMPRESSION_RATIO,
+			POPULATE_STATUS,
+			INMEMORY_PRIORITY,
+			INMEMORY_COMPRESSION
+		FROM (
+			SELECT
+				s.*,
+				ROW_NUMBER() OVER (PARTITION BY INST_ID ORDER BY BYTES_NOT_POPULATED DESC, BYTES DESC) AS SEGMENT_RANK
+			FROM gv$im_segments s
+		)`
+		if args.SegmentsTopN > 0 {
+			query += fmt.Sprintf(`
+		WHERE SEGMENT_RANK <= %d`, args.SegmentsTopN)
+		}
+		return query
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "inmemory.segment.sizeInBytes",
+			identifier:    "INMEMORY_SIZE",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.segment.diskSizeInBytes",
+			identifier:    "BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.segment.bytesNotPopulated",
+			identifier:    "BYTES_NOT_POPULATED",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.segment.compressionRatio",
+			identifier:    "COMPRESSION_RATIO",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.segment.populateStatus",
+			identifier:    "POPULATE_STATUS",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "inmemory.segment.priority",
+			identifier:    "INMEMORY_PRIORITY",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: false,
+		},
+		{
+			name:          "inmemory.segment.compression",
+			identifier:    "INMEMORY_COMPRESSION",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: false,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		metadata := map[string]string{
+			"instanceID":                            getInstanceIDString(row["INST_ID"]),
+			sampleMetadataKey:                       inMemorySegmentSample,
+			dimensionMetadataPrefix + "owner":       stringValue(row["OWNER"]),
+			dimensionMetadataPrefix + "segmentName": stringValue(row["SEGMENT_NAME"]),
+			dimensionMetadataPrefix + "segmentType": stringValue(row["SEGMENT_TYPE"]),
+		}
+		if partition := stringValue(row["PARTITION_NAME"]); partition != "" {
+			metadata[dimensionMetadataPrefix+"partitionName"] = partition
+		}
+		return metadata
+	}),
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+func TestInMemoryDisabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM gv\$parameter WHERE NAME = 'inmemory_size'`).
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+
+	var wg sync.WaitGroup
+	metricChan := make(chan newrelicMetricSender, 10)
+	wg.Add(1)
+	oracleInMemoryArea.Collect(database.NewDBWrapper(sqlx.NewDb(db, "sqlmock")), &wg, metricChan)
+	close(metricChan)
+
+	if len(metricChan) != 0 {
+		t.Errorf("Expected no metrics with inmemory_size set to 0, got %d", len(metricChan))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInMemoryArea(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM gv\$parameter WHERE NAME = 'inmemory_size'`).
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(2))
+	mock.ExpectQuery(`FROM gv\$inmemory_area`).
+		WillReturnRows(sqlmock.NewRows([]string{"INST_ID", "POOL", "ALLOC_BYTES", "USED_BYTES", "USED_PERCENT"}).
+			AddRow("1", "1MB POOL", 1048576000, 524288000, 50.0))
+
+	var wg sync.WaitGroup
+	metricChan := make(chan newrelicMetricSender, 10)
+	wg.Add(1)
+	oracleInMemoryArea.Collect(database.NewDBWrapper(sqlx.NewDb(db, "sqlmock")), &wg, metricChan)
+	close(metricChan)
+
+	values := make(map[string]interface{})
+	for sender := range metricChan {
+		if sender.metadata[sampleMetadataKey] != inMemoryPoolSample || sender.metadata[dimensionMetadataPrefix+"pool"] != "1MB POOL" {
+			t.Errorf("Unexpected metadata %v", sender.metadata)
+		}
+		values[sender.metric.name] = sender.metric.value
+	}
+	if values["inmemory.pool.populatedInBytes"] != int64(524288000) || values["inmemory.pool.populatedPercentage"] != 50.0 {
+		t.Errorf("Unexpected pool metrics %v", values)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInMemorySegments(t *testing.T) {
+	columns := []string{"INST_ID", "OWNER", "SEGMENT_NAME", "PARTITION_NAME", "SEGMENT_TYPE", "INMEMORY_SIZE", "BYTES", "BYTES_NOT_POPULATED", "COMPRESSION_RATIO", "POPULATE_STATUS", "INMEMORY_PRIORITY", "INMEMORY_COMPRESSION"}
+	senders := collectMetricGroup(t, oracleInMemorySegments, sqlmock.NewRows(columns).
+		AddRow("1", "SALES", "ORDERS", nil, "TABLE", 104857600, 419430400, 0, 4.0, "COMPLETED", "HIGH", "FOR QUERY LOW").
+		AddRow("1", "SALES", "ORDER_LINES", "P2024", "TABLE PARTITION", 0, 209715200, 209715200, nil, "STARTED", "NONE", "FOR QUERY LOW"))
+
+	values := make(map[string]interface{})
+	for _, sender := range senders {
+		if _, ok := sender.metadata[dimensionMetadataPrefix+"partitionName"]; ok != (sender.metadata[dimensionMetadataPrefix+"segmentName"] == "ORDER_LINES") {
+			t.Errorf("Unexpected partition dimension in %v", sender.metadata)
+		}
+		values[sender.metadata[dimensionMetadataPrefix+"segmentName"]+" "+sender.metric.name] = sender.metric.value
+	}
+
+	if values["ORDERS inmemory.segment.compressionRatio"] != 4.0 || values["ORDER_LINES inmemory.segment.populateStatus"] != "STARTED" {
+		t.Errorf("Unexpected segment metrics %v", values)
+	}
+	if _, ok := values["ORDER_LINES inmemory.segment.compressionRatio"]; ok {
+		t.Errorf("Expected no compression ratio for a segment not populated")
+	}
+}
+
+func TestInMemorySegments_TopN(t *testing.T) {
+	defer func(previous argumentList) { args = previous }(args)
+
+	args = argumentList{SegmentsTopN: 5}
+	if query := oracleInMemorySegments.sqlQuery(oracleInMemorySegments.metrics); !strings.Contains(query, "ORDER BY BYTES_NOT_POPULATED DESC") || !strings.Contains(query, "SEGMENT_RANK <= 5") {
+		t.Errorf("Expected the 5 segments with the most bytes not populated, got %s", query)
+	}
+
+	args = argumentList{}
+	if query := oracleInMemorySegments.sqlQuery(oracleInMemorySegments.metrics); strings.Contains(query, "SEGMENT_RANK <=") {
+		t.Errorf("Expected every segment when SEGMENTS_TOP_N is 0, got %s", query)
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const (
+	ioLatencySample       = "OracleIOLatencySample"
+	ioLatencyBucketSample = "OracleIOLatencyBucketSample"
+	ioFileTypeSample      = "OracleIOFileTypeSample"
+	ioFunctionSample      = "OracleIOFunctionSample"
+)
+
+// ioLatencyPercentiles are the percentiles reported for every wait event by their identifier
+var ioLatencyPercentiles = map[string]float64{
+	"P50": 0.50,
+	"P95": 0.95,
+	"P99": 0.99,
+}
+
+// oracleIOLatency sends a sample per histogram bucket, so it is only collected when enabled
+var oracleIOLatency = oracleMetricGroup{
+	name:  "io_latency",
+	optIn: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT INST_ID, EVENT, WAIT_TIME_MILLI, WAIT_COUNT
+		FROM gv$event_histogram
+		WHERE EVENT IN ('db file sequential read', 'db file scattered read', 'log file sync', 'log file parallel write')
+		ORDER BY INST_ID, EVENT, WAIT_TIME_MILLI`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "io.latency.bucket.waits",
+			identifier:    "WAIT_COUNT",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.latency.waits",
+			identifier:    "WAITS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.latency.p50InMilliseconds",
+			identifier:    "P50",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.latency.p95InMilliseconds",
+			identifier:    "P95",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.latency.p99InMilliseconds",
+			identifier:    "P99",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: ioLatencyGenerator,
+}
+
+// histogramDeltas completes the deltas of a histogram with the buckets missing from the
+// previous run, which had no waits then. It returns false when a known bucket went down
+func histogramDeltas(deltas, counters, previous map[string]float64) bool {
+	for name, value := range counters {
+		if _, ok := deltas[name]; ok {
+			continue
+		}
+		if _, ok := previous[name]; ok {
+			return false
+		}
+		deltas[name] = value
+	}
+	return true
+}
+
+// histogramBucket is the number of waits of an event shorter than a wait time,
+// and longer than half of it
+type histogramBucket struct {
+	event          string
+	waitTime       string
+	waitTimeMillis float64
+	waits          float64
+}
+
+// ioLatencyGenerator reports the waits of every gv$event_histogram bucket since the
+// previous run on a bucket sample, and the number of waits and the latency percentiles
+// of every event on a latency sample. Percentiles are the upper bound of the bucket
+// they fall in. The bucket counters are kept in the state store per instance, so
+// nothing is reported on the first run, nor on the run after a counter went down
+// with an instance restart, as the percentiles of a partial histogram are wrong.
+// A bucket seen for the first time, such as the first wait over a second, had no waits before
+func ioLatencyGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	var instanceIDs []string
+	buckets := make(map[string][]*histogramBucket)
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		instanceID := getInstanceIDString(rowMap["INST_ID"])
+		if _, ok := buckets[instanceID]; !ok {
+			instanceIDs = append(instanceIDs, instanceID)
+		}
+
+		b := &histogramBucket{event: stringValue(rowMap["EVENT"]), waitTime: stringValue(rowMap["WAIT_TIME_MILLI"])}
+		b.waitTimeMillis, _ = toFloat64(sanitizedValue(rowMap["WAIT_TIME_MILLI"]))
+		b.waits, _ = toFloat64(sanitizedValue(rowMap["WAIT_COUNT"]))
+		buckets[instanceID] = append(buckets[instanceID], b)
+	}
+
+	now := time.Now()
+	for _, instanceID := range instanceIDs {
+		counters := make(map[string]float64)
+		for _, b := range buckets[instanceID] {
+			counters[b.event+counterKeySeparator+b.waitTime] = b.waits
+		}
+
+		key := stateKey("io-latency", instanceID)
+		var previous counterSnapshot
+		if _, err := stateStore.Get(key, &previous); err != nil {
+			previous = counterSnapshot{}
+		}
+		deltas, elapsed := counterDeltas(key, counters, now)
+		if elapsed <= 0 || !histogramDeltas(deltas, counters, previous.Counters) {
+			continue
+		}
+
+		var events []string
+		eventBuckets := make(map[string][]*histogramBucket)
+		for _, b := range buckets[instanceID] {
+			delta, ok := deltas[b.event+counterKeySeparator+b.waitTime]
+			if !ok {
+				continue
+			}
+			if _, ok := eventBuckets[b.event]; !ok {
+				events = append(events, b.event)
+			}
+			eventBuckets[b.event] = append(eventBuckets[b.event], &histogramBucket{event: b.event, waitTime: b.waitTime, waitTimeMillis: b.waitTimeMillis, waits: delta})
+
+			sendIOLatencyMetrics(metrics, map[string]interface{}{"WAIT_COUNT": delta}, map[string]string{
+				"instanceID":                      instanceID,
+				sampleMetadataKey:                 ioLatencyBucketSample,
+				dimensionMetadataPrefix + "event": b.event,
+				dimensionMetadataPrefix + "waitTimeMilliseconds": b.waitTime,
+			}, metricChan)
+		}
+
+		for _, event := range events {
+			values := map[string]interface{}{"WAITS": histogramWaits(eventBuckets[event])}
+			for identifier, percentile := range ioLatencyPercentiles {
+				if value, ok := histogramPercentile(eventBuckets[event], percentile); ok {
+					values[identifier] = value
+				}
+			}
+
+			sendIOLatencyMetrics(metrics, values, map[string]string{
+				"instanceID":                      instanceID,
+				sampleMetadataKey:                 ioLatencySample,
+				dimensionMetadataPrefix + "event": event,
+			}, metricChan)
+		}
+	}
+
+	return nil
+}
+
+// sendIOLatencyMetrics sends the enabled metrics found in values with metadata
+func sendIOLatencyMetrics(metrics []*oracleMetric, values map[string]interface{}, metadata map[string]string, metricChan chan<- newrelicMetricSender) {
+	for _, metric := range metrics {
+		if value, ok := values[metric.identifier]; ok && metricEnabled(metric) {
+			metricChan <- newrelicMetricSender{
+				metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: value},
+				metadata: metadata,
+			}
+		}
+	}
+}
+
+// histogramWaits returns the number of waits of all buckets
+func histogramWaits(buckets []*histogramBucket) float64 {
+	var waits float64
+	for _, b := range buckets {
+		waits += b.waits
+	}
+	return waits
+}
+
+// histogramPercentile returns the wait time of the bucket the percentile falls in.
+// Buckets must be sorted by wait time, and at least one wait is needed
+func histogramPercentile(buckets []*histogramBucket, percentile float64) (float64, bool) {
+	total := histogramWaits(buckets)
+	if total <= 0 {
+		return 0, false
+	}
+
+	var cumulative float64
+	for _, b := range buckets {
+		cumulative += b.waits
+		if cumulative >= percentile*total {
+			return b.waitTimeMillis, true
+		}
+	}
+	return buckets[len(buckets)-1].waitTimeMillis, true
+}
+
+var oracleIOFileTypes = oracleMetricGroup{
+	name: "io_file_types",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			INST_ID,
+			FILETYPE_NAME,
+			SUM(SMALL_READ_MEGABYTES + LARGE_READ_MEGABYTES) * 1048576 AS READ_BYTES,
+			SUM(SMALL_WRITE_MEGABYTES + LARGE_WRITE_MEGABYTES) * 1048576 AS WRITE_BYTES,
+			SUM(SMALL_READ_REQS + LARGE_READ_REQS) AS READ_REQUESTS,
+			SUM(SMALL_WRITE_REQS + LARGE_WRITE_REQS) AS WRITE_REQUESTS
+		FROM gv$iostat_file
+		GROUP BY INST_ID, FILETYPE_NAME`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "io.fileType.readBytesPerSecond",
+			identifier:    "READ_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.fileType.writeBytesPerSecond",
+			identifier:    "WRITE_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.fileType.readRequestsPerSecond",
+			identifier:    "READ_REQUESTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.fileType.writeRequestsPerSecond",
+			identifier:    "WRITE_REQUESTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: counterRatesGenerator("io-file-types", func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			sampleMetadataKey:                    ioFileTypeSample,
+			dimensionMetadataPrefix + "fileType": stringValue(row["FILETYPE_NAME"]),
+		}
+	}),
+}
+
+var oracleIOFunctions = oracleMetricGroup{
+	name: "io_functions",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			INST_ID,
+			FUNCTION_NAME,
+			(SMALL_READ_MEGABYTES + LARGE_READ_MEGABYTES) * 1048576 AS READ_BYTES,
+			(SMALL_WRITE_MEGABYTES + LARGE_WRITE_MEGABYTES) * 1048576 AS WRITE_BYTES,
+			SMALL_READ_REQS + LARGE_READ_REQS AS READ_REQUESTS,
+			SMALL_WRITE_REQS + LARGE_WRITE_REQS AS WRITE_REQUESTS,
+			NUMBER_OF_WAITS AS WAITS,
+			WAIT_TIME AS WAIT_TIME_MS
+		FROM gv$iostat_function`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "io.function.readBytesPerSecond",
+			identifier:    "READ_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.function.writeBytesPerSecond",
+			identifier:    "WRITE_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.function.readRequestsPerSecond",
+			identifier:    "READ_REQUESTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.function.writeRequestsPerSecond",
+			identifier:    "WRITE_REQUESTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "io.function.waitsPerSecond",
+			identifier:    "WAITS",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "io.function.waitTimeInMillisecondsPerSecond",
+			identifier:    "WAIT_TIME_MS",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+	},
+
+	metricsGenerator: counterRatesGenerator("io-functions", func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			sampleMetadataKey:                    ioFunctionSample,
+			dimensionMetadataPrefix + "function": stringValue(row["FUNCTION_NAME"]),
+		}
+	}),
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+func TestIOLatency(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "EVENT", "WAIT_TIME_MILLI", "WAIT_COUNT"}
+	senders := collectMetricGroup(t, oracleIOLatency, sqlmock.NewRows(columns).
+		AddRow("1", "db file sequential read", 1, 100).
+		AddRow("1", "db file sequential read", 2, 50).
+		AddRow("1", "db file sequential read", 4, 10).
+		AddRow("1", "db file sequential read", 8, 0))
+	if len(senders) != 0 {
+		t.Fatalf("Expected no metrics on the first run, got %d", len(senders))
+	}
+	rewindCounters(t, stateKey("io-latency", "1"), 60)
+
+	senders = collectMetricGroup(t, oracleIOLatency, sqlmock.NewRows(columns).
+		AddRow("1", "db file sequential read", 1, 190).
+		AddRow("1", "db file sequential read", 2, 55).
+		AddRow("1", "db file sequential read", 4, 13).
+		AddRow("1", "db file sequential read", 8, 2))
+
+	buckets := make(map[string]interface{})
+	latency := make(map[string]interface{})
+	for _, sender := range senders {
+		if sender.metadata[dimensionMetadataPrefix+"event"] != "db file sequential read" || sender.metadata["instanceID"] != "1" {
+			t.Errorf("Unexpected metadata %v", sender.metadata)
+		}
+		switch sender.metadata[sampleMetadataKey] {
+		case ioLatencyBucketSample:
+			buckets[sender.metadata[dimensionMetadataPrefix+"waitTimeMilliseconds"]] = sender.metric.value
+		case ioLatencySample:
+			latency[sender.metric.name] = sender.metric.value
+		}
+	}
+
+	expectedBuckets := map[string]interface{}{"1": 90.0, "2": 5.0, "4": 3.0, "8": 2.0}
+	for bucket, waits := range expectedBuckets {
+		if buckets[bucket] != waits {
+			t.Errorf("Expected %v waits in bucket %s, got %v", waits, bucket, buckets[bucket])
+		}
+	}
+
+	expectedLatency := map[string]interface{}{
+		"io.latency.waits":             100.0,
+		"io.latency.p50InMilliseconds": 1.0,
+		"io.latency.p95InMilliseconds": 2.0,
+		"io.latency.p99InMilliseconds": 8.0,
+	}
+	for name, value := range expectedLatency {
+		if latency[name] != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, latency[name])
+		}
+	}
+}
+
+func TestIOLatency_CounterReset(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "EVENT", "WAIT_TIME_MILLI", "WAIT_COUNT"}
+	collectMetricGroup(t, oracleIOLatency, sqlmock.NewRows(columns).
+		AddRow("1", "log file sync", 1, 1000).
+		AddRow("1", "log file sync", 2, 10))
+	rewindCounters(t, stateKey("io-latency", "1"), 60)
+
+	// The instance restarted, and only the bucket of 2 milliseconds went past its old count
+	senders := collectMetricGroup(t, oracleIOLatency, sqlmock.NewRows(columns).
+		AddRow("1", "log file sync", 1, 5).
+		AddRow("1", "log file sync", 2, 20))
+	if len(senders) != 0 {
+		t.Fatalf("Expected no metrics after a counter reset, got %d", len(senders))
+	}
+	rewindCounters(t, stateKey("io-latency", "1"), 60)
+
+	senders = collectMetricGroup(t, oracleIOLatency, sqlmock.NewRows(columns).
+		AddRow("1", "log file sync", 1, 95).
+		AddRow("1", "log file sync", 2, 30))
+	for _, sender := range senders {
+		if sender.metric.name == "io.latency.waits" && sender.metric.value != 100.0 {
+			t.Errorf("Expected 100 waits from the counters after the reset, got %v", sender.metric.value)
+		}
+	}
+	if len(senders) == 0 {
+		t.Error("Expected metrics from the counters after the reset")
+	}
+}
+
+func TestIOLatency_NewBucket(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "EVENT", "WAIT_TIME_MILLI", "WAIT_COUNT"}
+	collectMetricGroup(t, oracleIOLatency, sqlmock.NewRows(columns).
+		AddRow("1", "log file sync", 1, 90).
+		AddRow("1", "log file sync", 2, 10))
+	rewindCounters(t, stateKey("io-latency", "1"), 60)
+
+	// The first waits over a second add the bucket of 1024 milliseconds
+	senders := collectMetricGroup(t, oracleIOLatency, sqlmock.NewRows(columns).
+		AddRow("1", "log file sync", 1, 180).
+		AddRow("1", "log file sync", 2, 15).
+		AddRow("1", "log file sync", 1024, 5))
+
+	buckets := make(map[string]interface{})
+	latency := make(map[string]interface{})
+	for _, sender := range senders {
+		switch sender.metadata[sampleMetadataKey] {
+		case ioLatencyBucketSample:
+			buckets[sender.metadata[dimensionMetadataPrefix+"waitTimeMilliseconds"]] = sender.metric.value
+		case ioLatencySample:
+			latency[sender.metric.name] = sender.metric.value
+		}
+	}
+	if buckets["1024"] != 5.0 {
+		t.Errorf("Expected the wait of the new bucket, got %v", buckets)
+	}
+	if latency["io.latency.waits"] != 100.0 || latency["io.latency.p99InMilliseconds"] != 1024.0 {
+		t.Errorf("Expected the new bucket in the percentiles, got %v", latency)
+	}
+}
+
+func TestIOFileTypes(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+	defer func(previous argumentList) { args = previous }(args)
+	args = argumentList{ContentionTopN: 1}
+
+	columns := []string{"INST_ID", "FILETYPE_NAME", "READ_BYTES", "WRITE_BYTES", "READ_REQUESTS", "WRITE_REQUESTS"}
+	collectMetricGroup(t, oracleIOFileTypes, sqlmock.NewRows(columns).
+		AddRow("1", "Data File", 1048576, 0, 100, 0).
+		AddRow("1", "Log File", 0, 2097152, 0, 200))
+	rewindCounters(t, stateKey("counter-rates-io-file-types", "1"), 10)
+
+	senders := collectMetricGroup(t, oracleIOFileTypes, sqlmock.NewRows(columns).
+		AddRow("1", "Data File", 11534336, 0, 600, 0).
+		AddRow("1", "Log File", 0, 2097152, 0, 200))
+
+	values := make(map[string]float64)
+	for _, sender := range senders {
+		values[sender.metadata[dimensionMetadataPrefix+"fileType"]+" "+sender.metric.name] = sender.metric.value.(float64)
+	}
+	if len(values) != 8 {
+		t.Errorf("Expected every file type regardless of CONTENTION_TOP_N, got %v", values)
+	}
+
+	expected := map[string]float64{
+		"Data File io.fileType.readBytesPerSecond":    1048576,
+		"Data File io.fileType.readRequestsPerSecond": 50,
+		"Log File io.fileType.writeBytesPerSecond":    0,
+	}
+	for name, value := range expected {
+		if math.Abs(values[name]-value) > value/100 {
+			t.Errorf("Expected %s to be about %v, got %v", name, value, values[name])
+		}
+	}
+}
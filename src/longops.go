@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const (
+	longOperationInProgress = "Long operation in progress"
+	longOperationCompleted  = "Long operation completed"
+)
+
+var oracleLongOperations = oracleMetricGroup{
+	name: "long_operations",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Operations completed in the last hour are fetched so they can be reported once.
+		// Unfinished operations are only kept while their session is alive, as the ones
+		// cancelled or left by a dead session stay in gv$session_longops
+		return `
+		SELECT
+			i.INST_ID,
+			l.SID,
+			l.SERIAL#,
+			l.OPNAME,
+			l.TARGET,
+			l.SOFAR,
+			l.TOTALWORK,
+			l.ELAPSED_SECONDS,
+			l.TIME_REMAINING,
+			l.USERNAME,
+			l.SQL_ID,
+			l.START_TIME
+		FROM gv$instance i
+		LEFT JOIN (
+			SELECT
+				l.INST_ID,
+				l.SID,
+				l.SERIAL#,
+				l.OPNAME,
+				l.TARGET,
+				l.SOFAR,
+				l.TOTALWORK,
+				l.ELAPSED_SECONDS,
+				l.TIME_REMAINING,
+				l.USERNAME,
+				l.SQL_ID,
+				TO_CHAR(l.START_TIME, 'YYYY-MM-DD HH24:MI:SS') AS START_TIME
+			FROM gv$session_longops l
+			LEFT JOIN gv$session s ON s.INST_ID = l.INST_ID AND s.SID = l.SID AND s.SERIAL# = l.SERIAL#
+			WHERE l.TOTALWORK > 0
+				AND ((l.SOFAR < l.TOTALWORK AND s.SID IS NOT NULL) OR (l.SOFAR >= l.TOTALWORK AND l.LAST_UPDATE_TIME > SYSDATE - 1/24))
+		) l ON l.INST_ID = i.INST_ID`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "db.longOperations",
+			identifier:    "LONG_OPERATIONS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	events: []string{longOperationInProgress, longOperationCompleted},
+
+	metricsGenerator: longOperationsGenerator,
+}
+
+// longOperationsGenerator counts the unfinished long operations of every instance and
+// sends an event when an operation is first seen in progress and when it completes.
+// The summary last reported for every operation is kept in the state store
+func longOperationsGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	key := stateKey("long-operations", "reported")
+	var reported map[string]string
+	if _, err := stateStore.Get(key, &reported); err != nil {
+		reported = nil
+	}
+
+	var instanceIDs []string
+	inProgress := make(map[string]int)
+	current := make(map[string]string)
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		instanceID := getInstanceIDString(rowMap["INST_ID"])
+		if _, ok := inProgress[instanceID]; !ok {
+			instanceIDs = append(instanceIDs, instanceID)
+			inProgress[instanceID] = 0
+		}
+
+		// Instances without long operations have a single row of NULLs
+		if rowMap["SID"] == nil {
+			continue
+		}
+
+		soFar, _ := toFloat64(sanitizedValue(rowMap["SOFAR"]))
+		totalWork, _ := toFloat64(sanitizedValue(rowMap["TOTALWORK"]))
+
+		// Operations without a known amount of work never count as completed
+		summary := longOperationInProgress
+		if totalWork > 0 && soFar >= totalWork {
+			summary = longOperationCompleted
+		} else {
+			inProgress[instanceID]++
+		}
+
+		operation := fmt.Sprintf("%s|%s|%s|%s|%s", instanceID, stringValue(rowMap["SID"]), stringValue(rowMap["SERIAL#"]), stringValue(rowMap["OPNAME"]), stringValue(rowMap["START_TIME"]))
+		current[operation] = summary
+		if reported[operation] == summary {
+			continue
+		}
+
+		metricChan <- newrelicMetricSender{
+			metadata: map[string]string{"instanceID": instanceID},
+			event:    event.NewWithAttributes(summary, event.NotificationEventCategory, longOperationAttributes(rowMap, soFar, totalWork)),
+		}
+	}
+
+	// Only the operations still returned by the query are kept, since they are not
+	// returned again once they are an hour old
+	stateStore.Set(key, current)
+
+	for _, metric := range metrics {
+		if !metricEnabled(metric) {
+			continue
+		}
+		for _, instanceID := range instanceIDs {
+			metricChan <- newrelicMetricSender{
+				metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: inProgress[instanceID]},
+				metadata: map[string]string{"instanceID": instanceID},
+			}
+		}
+	}
+
+	return nil
+}
+
+// longOperationAttributes returns the event attributes of a gv$session_longops row
+func longOperationAttributes(row map[string]interface{}, soFar float64, totalWork float64) map[string]interface{} {
+	attributes := map[string]interface{}{
+		"opname":    stringValue(row["OPNAME"]),
+		"target":    stringValue(row["TARGET"]),
+		"username":  stringValue(row["USERNAME"]),
+		"sqlId":     stringValue(row["SQL_ID"]),
+		"sid":       stringValue(row["SID"]),
+		"serial":    stringValue(row["SERIAL#"]),
+		"startTime": stringValue(row["START_TIME"]),
+	}
+	if totalWork > 0 {
+		attributes["percentDone"] = soFar / totalWork * 100
+	}
+	if elapsed, ok := toFloat64(sanitizedValue(row["ELAPSED_SECONDS"])); ok {
+		attributes["elapsedSeconds"] = elapsed
+	}
+	if remaining, ok := toFloat64(sanitizedValue(row["TIME_REMAINING"])); ok {
+		attributes["timeRemainingSeconds"] = remaining
+	}
+	return attributes
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+func TestLongOperations(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "SID", "SERIAL#", "OPNAME", "TARGET", "SOFAR", "TOTALWORK", "ELAPSED_SECONDS", "TIME_REMAINING", "USERNAME", "SQL_ID", "START_TIME"}
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).
+			AddRow(1, 10, 100, "RMAN: full datafile backup", "+DATA/users01.dbf", 250, 1000, 60, 180, "SYS", nil, "2024-01-01 01:00:00").
+			AddRow(1, 11, 200, "Gather Table's Index Statistics", "HR.EMPLOYEES", 500, 500, 30, 0, "HR", "7h35uxf5uhmm1", "2024-01-01 00:50:00").
+			AddRow(2, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	}
+
+	senders := collectMetricGroup(t, oracleLongOperations, rows())
+
+	events := make(map[string]map[string]interface{})
+	counts := make(map[string]interface{})
+	for _, sender := range senders {
+		if sender.event != nil {
+			events[sender.event.Summary] = sender.event.Attributes
+			continue
+		}
+		counts[sender.metadata["instanceID"]] = sender.metric.value
+	}
+
+	if counts["1"] != 1 || counts["2"] != 0 {
+		t.Errorf("Expected 1 long operation on instance 1 and none on instance 2, got %v", counts)
+	}
+
+	inProgress := events[longOperationInProgress]
+	if inProgress["opname"] != "RMAN: full datafile backup" || inProgress["percentDone"] != float64(25) || inProgress["timeRemainingSeconds"] != float64(180) || inProgress["sqlId"] != "" {
+		t.Errorf("Unexpected in progress event attributes %v", inProgress)
+	}
+	if completed := events[longOperationCompleted]; completed["target"] != "HR.EMPLOYEES" || completed["percentDone"] != float64(100) {
+		t.Errorf("Unexpected completed event attributes %v", completed)
+	}
+
+	senders = collectMetricGroup(t, oracleLongOperations, rows())
+	for _, sender := range senders {
+		if sender.event != nil {
+			t.Errorf("Expected operations already reported not to send events again, got %s", sender.event.Summary)
+		}
+	}
+
+	// The backup completes
+	senders = collectMetricGroup(t, oracleLongOperations, sqlmock.NewRows(columns).
+		AddRow(1, 10, 100, "RMAN: full datafile backup", "+DATA/users01.dbf", 1000, 1000, 240, 0, "SYS", nil, "2024-01-01 01:00:00"))
+	var summaries []string
+	for _, sender := range senders {
+		if sender.event != nil {
+			summaries = append(summaries, sender.event.Summary)
+		}
+	}
+	if len(summaries) != 1 || summaries[0] != longOperationCompleted {
+		t.Errorf("Expected a single completed event, got %v", summaries)
+	}
+}
+
+func TestLongOperations_Query(t *testing.T) {
+	query := oracleLongOperations.sqlQuery(oracleLongOperations.metrics)
+	if !strings.Contains(query, "LEFT JOIN gv$session s") || !strings.Contains(query, "l.SOFAR < l.TOTALWORK AND s.SID IS NOT NULL") {
+		t.Errorf("Expected unfinished operations to be limited to live sessions, got %s", query)
+	}
+}
+
+func TestLongOperations_NoTotalWork(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "SID", "SERIAL#", "OPNAME", "TARGET", "SOFAR", "TOTALWORK", "ELAPSED_SECONDS", "TIME_REMAINING", "USERNAME", "SQL_ID", "START_TIME"}
+	senders := collectMetricGroup(t, oracleLongOperations, sqlmock.NewRows(columns).
+		AddRow(1, 12, 300, "Hash Join", "", 0, 0, 20, nil, "APP", nil, "2024-01-01 02:00:00"))
+
+	for _, sender := range senders {
+		if sender.event != nil && sender.event.Summary != longOperationInProgress {
+			t.Errorf("Expected an operation without total work to be in progress, got %s", sender.event.Summary)
+		}
+		if sender.event == nil && sender.metric.value != 1 {
+			t.Errorf("Expected the operation to be counted, got %v", sender.metric.value)
+		}
+	}
+}
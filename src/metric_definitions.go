@@ -1,18 +1,40 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/godror/godror"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
 	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
 	"github.com/newrelic/infra-integrations-sdk/v3/log"
 	"github.com/newrelic/nri-oracledb/src/database"
 )
 
-const defaultCustomSampleType = "OracleCustomSample"
+const (
+	defaultCustomSampleType     = "OracleCustomSample"
+	defaultCustomInstanceColumn = "INST_ID"
+	defaultCustomEntityType     = "instance"
+
+	// maxAttributeLength is the longest string attribute value accepted by New Relic
+	maxAttributeLength = 4095
+	// secondsPerMonth is the average length of a month, used to convert INTERVAL YEAR TO MONTH values
+	secondsPerMonth = 365.25 * 24 * 60 * 60 / 12
+
+	// sampleMetadataKey sets the sample type of metrics reported on their own metric
+	// set, with one set per combination of the metadata entries prefixed by dimensionMetadataPrefix
+	sampleMetadataKey       = "sample"
+	dimensionMetadataPrefix = "dimension."
+	// instanceDimension and remoteInstanceDimension hold instance IDs that are
+	// reported as instance names
+	instanceDimension       = "instance"
+	remoteInstanceDimension = "remoteInstance"
+)
 
 // oracleMetric is a storage struct for the information needed to parse
 // a metric from a query and create a newrelicMetric
@@ -40,6 +62,8 @@ type newrelicMetricSender struct {
 	isCustom            bool
 	customMetrics       []map[string]interface{}
 	metricTypeOverrides map[string]metricType
+	customColumns       map[string]customColumn
+	event               *event.Event
 }
 
 // oracleMetricGroup is a struct that contains all the information needed
@@ -51,6 +75,22 @@ type oracleMetricGroup struct {
 	sqlQuery         func([]*oracleMetric) string
 	metrics          []*oracleMetric
 	metricsGenerator func(database.Rows, []*oracleMetric, chan<- newrelicMetricSender) error
+	// events lists the summaries of the events reported by the group, if any
+	events []string
+	// entityType is the entity type the group reports on, when it is neither an
+	// instance nor a tablespace
+	entityType string
+	// slow groups are expensive and run at most once every SLOW_METRICS_INTERVAL
+	slow bool
+	// optIn groups are heavy and only run when listed in ENABLE_METRICS_GROUPS or
+	// INCLUDE_METRICS_GROUPS
+	optIn bool
+	// enabledQuery returns a single number, and the group is skipped when it is zero or
+	// the query fails, such as when the feature covered by the group is not in use
+	enabledQuery string
+	// maxVersion is the latest database release the group runs on, compared on as many
+	// components as it has, so a maxVersion of 11 includes every 11.x release
+	maxVersion string
 }
 
 // Collect is a method on oracleMetricGroups which collects the metrics defined
@@ -58,6 +98,20 @@ type oracleMetricGroup struct {
 func (mg *oracleMetricGroup) Collect(db database.DBWrapper, wg *sync.WaitGroup, metricChan chan<- newrelicMetricSender) {
 	defer wg.Done()
 
+	if mg.slow && !slowMetricGroupDue(mg.name) {
+		log.Debug("Metric group %s ran less than %s ago, skipping", mg.name, slowMetricsInterval)
+		return
+	}
+
+	if mg.maxVersion != "" && oracleVersion != "" && compareVersionPrefix(oracleVersion, mg.maxVersion) > 0 {
+		log.Debug("Metric group %s does not apply to database version %s, skipping", mg.name, oracleVersion)
+		return
+	}
+
+	if mg.enabledQuery != "" && !metricGroupEnabled(mg, db) {
+		return
+	}
+
 	handleLockedAccountsMetricGroup(mg, db)
 	query := mg.sqlQuery(mg.metrics)
 
@@ -75,6 +129,73 @@ func (mg *oracleMetricGroup) Collect(db database.DBWrapper, wg *sync.WaitGroup,
 		log.Error("Failed to generate metrics from db response for query %s: %s", formatQueryForLogging(query), err)
 		return
 	}
+
+	if mg.slow {
+		markSlowMetricGroupRun(mg.name)
+	}
+}
+
+// slowMetricGroupDue reports whether a slow metric group has to run in this execution,
+// which is when SLOW_METRICS_INTERVAL has elapsed since its last successful run
+func slowMetricGroupDue(name string) bool {
+	var lastRun int64
+	if _, err := stateStore.Get(stateKey("slow-group", name), &lastRun); err == nil && time.Since(time.Unix(lastRun, 0)) < slowMetricsInterval {
+		return false
+	}
+	return true
+}
+
+// markSlowMetricGroupRun records a successful run of a slow metric group, so a failed
+// run is retried in the next execution
+func markSlowMetricGroupRun(name string) {
+	stateStore.Set(stateKey("slow-group", name), time.Now().Unix())
+}
+
+// metricGroupEnabled runs the enabled query of a metric group and reports whether
+// it returned a number other than zero
+func metricGroupEnabled(mg *oracleMetricGroup, db database.DBWrapper) bool {
+	var enabled float64
+	if err := db.QueryRow(mg.enabledQuery).Scan(&enabled); err != nil {
+		log.Debug("Metric group %s is not available, skipping: %s", mg.name, err)
+		return false
+	}
+	if enabled == 0 {
+		log.Debug("Metric group %s is not in use, skipping", mg.name)
+		return false
+	}
+	return true
+}
+
+// metricEnabled reports whether a metric should be collected. Metrics matching
+// INCLUDE_METRICS are collected even when they are not default metrics and
+// EXTENDED_METRICS is disabled, while metrics matching EXCLUDE_METRICS are never collected
+func metricEnabled(metric *oracleMetric) bool {
+	if matchesAnyPattern(metric.name, excludeMetricPatterns) {
+		return false
+	}
+
+	return metric.defaultMetric || args.ExtendedMetrics || matchesAnyPattern(metric.name, includeMetricPatterns)
+}
+
+// metricByIdentifier returns the metric of a group read from the identifier column
+// or row, or nil when the group has none
+func metricByIdentifier(metrics []*oracleMetric, identifier string) *oracleMetric {
+	for _, metric := range metrics {
+		if metric.identifier == identifier {
+			return metric
+		}
+	}
+	return nil
+}
+
+// matchesAnyPattern reports whether name matches any of the glob patterns
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
 }
 
 // This function is necessary because of how sql-mock auto-converts
@@ -88,6 +209,8 @@ func getInstanceIDString(originalID interface{}) string {
 		return strconv.Itoa(id)
 	case int64:
 		return strconv.FormatInt(id, 10)
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
 	case string:
 		return id
 	default:
@@ -123,7 +246,7 @@ func columnMetricsGenerator(rows database.Rows, metrics []*oracleMetric, metricC
 
 		// Create each metric in the list of metrics we want to collect
 		for _, metric := range metrics {
-			if metric.defaultMetric || args.ExtendedMetrics {
+			if metricEnabled(metric) {
 				newMetric := &newrelicMetric{
 					name:       metric.name,
 					metricType: metric.metricType,
@@ -141,6 +264,96 @@ func columnMetricsGenerator(rows database.Rows, metrics []*oracleMetric, metricC
 	return nil
 }
 
+// scanRowMap scans the current row into a map keyed by column name
+func scanRowMap(rows database.Rows, columnNames []string) (map[string]interface{}, error) {
+	columns := make([]interface{}, len(columnNames))
+	pointers := make([]interface{}, len(columnNames))
+	for i := 0; i < len(columnNames); i++ {
+		pointers[i] = &columns[i]
+	}
+
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	rowMap := make(map[string]interface{}, len(columnNames))
+	for i, column := range columnNames {
+		rowMap[column] = columns[i]
+	}
+	return rowMap, nil
+}
+
+// keyedColumnMetricsGenerator returns a metrics generator like columnMetricsGenerator
+// that routes the metrics of every row with the metadata returned by metadata.
+// NULL columns are not reported
+func keyedColumnMetricsGenerator(metadata func(map[string]interface{}) map[string]string) func(database.Rows, []*oracleMetric, chan<- newrelicMetricSender) error {
+	return func(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+		columnNames, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve columns from rows")
+		}
+
+		for rows.Next() {
+			rowMap, err := scanRowMap(rows, columnNames)
+			if err != nil {
+				return err
+			}
+
+			rowMetadata := metadata(rowMap)
+			for _, metric := range metrics {
+				if value := rowMap[metric.identifier]; value != nil && metricEnabled(metric) {
+					metricChan <- newrelicMetricSender{
+						metric: &newrelicMetric{
+							name:       metric.name,
+							metricType: metric.metricType,
+							value:      value,
+						},
+						metadata: rowMetadata,
+					}
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+// keyedRowMetricsGenerator returns a metrics generator like rowMetricsGenerator for
+// rows with NAME and VALUE columns, that routes the metrics of every row with the
+// metadata returned by metadata
+func keyedRowMetricsGenerator(metadata func(map[string]interface{}) map[string]string) func(database.Rows, []*oracleMetric, chan<- newrelicMetricSender) error {
+	return func(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+		columnNames, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve columns from rows")
+		}
+
+		for rows.Next() {
+			rowMap, err := scanRowMap(rows, columnNames)
+			if err != nil {
+				return err
+			}
+
+			name := stringValue(rowMap["NAME"])
+			for _, metric := range metrics {
+				if metric.identifier == name && metricEnabled(metric) {
+					metricChan <- newrelicMetricSender{
+						metric: &newrelicMetric{
+							name:       metric.name,
+							metricType: metric.metricType,
+							value:      rowMap["VALUE"],
+						},
+						metadata: metadata(rowMap),
+					}
+					break
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
 // inMetrics is a function to build a WHERE IN ('metric1', 'metric2', 'metric...') string
 // This is appended to certain queries in order to return only the metrics included in oracleMetric array
 func inMetrics(field string, metrics []*oracleMetric) string {
@@ -242,24 +455,43 @@ func (mg *customMetricGroup) Collect(db database.DBWrapper, wg *sync.WaitGroup,
 		}
 	}
 
-	rowsCustom, err := db.Queryx(mg.Query)
+	if err := checkCustomQuery(mg.Query); err != nil {
+		log.Error("Refusing to run custom query %s: %s", formatQueryForLogging(mg.Query), err)
+		return
+	}
+
+	tx, err := db.BeginReadOnly(context.Background())
+	if err != nil {
+		log.Error("Failed to start read-only transaction for query %s: %s", formatQueryForLogging(mg.Query), err)
+		return
+	}
+	defer tx.Rollback()
+
+	rowsCustom, err := tx.QueryxContext(context.Background(), mg.Query)
 	if err != nil {
 		log.Error("Failed to execute query %s: %s", formatQueryForLogging(mg.Query), err)
 		return
 	}
 	defer rowsCustom.Close()
 
+	columns, err := rowsCustom.Columns()
+	if err != nil {
+		log.Error("Failed to retrieve columns of custom query %s: %s", formatQueryForLogging(mg.Query), err)
+		return
+	}
+
 	sender := newrelicMetricSender{
 		isCustom: true,
 		metadata: map[string]string{
-			"instanceID": instanceID,
-			"sampleName": defaultCustomSampleType,
+			"instanceID":     instanceID,
+			"instanceColumn": customInstanceColumn(columns, ""),
+			"sampleName":     defaultCustomSampleType,
 		},
 	}
 
-	for rows.Next() {
+	for rowsCustom.Next() {
 		row := make(map[string]interface{})
-		err := rows.MapScan(row)
+		err := rowsCustom.MapScan(row)
 		if err != nil {
 			log.Error("Failed to scan custom query row: %s", err)
 			return
@@ -267,7 +499,9 @@ func (mg *customMetricGroup) Collect(db database.DBWrapper, wg *sync.WaitGroup,
 
 		convertedMetrics := make(map[string]interface{})
 		for key, val := range row {
-			convertedMetrics[key] = sanitizeValue(val)
+			if sanitized, ok := sanitizeValue(val); ok {
+				convertedMetrics[key] = sanitized
+			}
 		}
 
 		sender.customMetrics = append(sender.customMetrics, convertedMetrics)
@@ -740,7 +974,7 @@ var oracleRedoLogWaits = oracleMetricGroup{
 
 			// Match the metric to one of the metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					if strings.Contains(sysScanner.event, metric.identifier) {
 						newMetric := &newrelicMetric{
 							name:       metric.name,
@@ -815,7 +1049,7 @@ var oraclePDBDatafilesOffline = oracleMetricGroup{
 
 			// Create each metric in the list of metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						metricType: metric.metricType,
@@ -886,7 +1120,7 @@ var oracleCDBDatafilesOffline = oracleMetricGroup{
 
 			// Create each metric in the list of metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						metricType: metric.metricType,
@@ -962,7 +1196,7 @@ var oracleLockedAccounts = oracleMetricGroup{
 
 			// Create each metric in the list of metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						metricType: metric.metricType,
@@ -1030,7 +1264,7 @@ var oraclePDBNonWrite = oracleMetricGroup{
 
 			// Create each metric in the list of metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						metricType: metric.metricType,
@@ -1128,7 +1362,7 @@ var oracleTablespaceMetrics = oracleMetricGroup{
 
 			// Create each metric in the list of metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						metricType: metric.metricType,
@@ -1187,7 +1421,7 @@ var globalNameInstanceMetric = oracleMetricGroup{
 
 			// Match the metric to one of the metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						value:      tempPgaRow.value,
@@ -1246,7 +1480,7 @@ var globalNameTablespaceMetric = oracleMetricGroup{
 
 			// Match the metric to one of the metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						value:      tempPgaRow.value,
@@ -1303,7 +1537,7 @@ var dbIDInstanceMetric = oracleMetricGroup{
 
 			// Match the metric to one of the metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						value:      tempPgaRow.value,
@@ -1362,7 +1596,7 @@ var dbIDTablespaceMetric = oracleMetricGroup{
 
 			// Match the metric to one of the metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						value:      tempPgaRow.value,
@@ -1466,7 +1700,7 @@ var oracleReadWriteMetrics = oracleMetricGroup{
 
 			// Create each new metric
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
 						metricType: metric.metricType,
@@ -1537,7 +1771,7 @@ var oraclePgaMetrics = oracleMetricGroup{
 
 			// Match the metric to one of the metrics we want to collect
 			for _, metric := range metrics {
-				if metric.defaultMetric || args.ExtendedMetrics {
+				if metricEnabled(metric) {
 					if tempPgaRow.name == metric.identifier {
 						newMetric := &newrelicMetric{
 							name:       metric.name,
@@ -2748,7 +2982,7 @@ func rowMetricsGenerator(rows database.Rows, metrics []*oracleMetric, metricsCha
 
 		// Match the metric to one of the metrics we want to collect
 		for _, metric := range metrics {
-			if metric.defaultMetric || args.ExtendedMetrics {
+			if metricEnabled(metric) {
 				if sysScanner.metricName == metric.identifier {
 					newMetric := &newrelicMetric{
 						name:       metric.name,
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/godror/godror"
 	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
@@ -18,53 +25,115 @@ import (
 )
 
 type metricsCollector struct {
-	integration         *integration.Integration
-	db                  database.DBWrapper
-	wg                  *sync.WaitGroup
-	instanceLookUp      map[string]string
-	customMetricsQuery  string
-	customMetricsConfig string
-	skipMetricsGroups   []string
+	integration          *integration.Integration
+	db                   database.DBWrapper
+	wg                   *sync.WaitGroup
+	instanceLookUp       map[string]string
+	customMetricsQuery   string
+	customMetricsConfig  string
+	skipMetricsGroups    []string
+	includeMetricsGroups []string
+	enableMetricsGroups  []string
 }
 
-// collect spins off goroutines for each of the metric groups, which
-// send their metrics to the populateMetrics goroutine
-func (mc *metricsCollector) collect() {
+// tablespaceMetricGroups are the metric groups reported on ora-tablespace entities
+var tablespaceMetricGroups = []oracleMetricGroup{
+	oracleTablespaceMetrics,
+	globalNameTablespaceMetric,
+	dbIDTablespaceMetric,
+	oracleCDBDatafilesOffline,
+	oraclePDBDatafilesOffline,
+	oraclePDBNonWrite,
+	oracleTablespaceStateEvents,
+	oracleDatafileStateEvents,
+	oracleDatafiles,
+	oracleTablespaceGrowth,
+	oracleSegments,
+}
+
+// instanceMetricGroups are the metric groups reported on ora-instance entities
+var instanceMetricGroups = []oracleMetricGroup{
+	oracleLockedAccounts,
+	oracleReadWriteMetrics,
+	oraclePgaMetrics,
+	globalNameInstanceMetric,
+	dbIDInstanceMetric,
+	oracleLongRunningQueries,
+	oracleSGAUGATotalMemory,
+	oracleSGASharedPoolLibraryCacheSharableStatement,
+	oracleSGASharedPoolLibraryCacheShareableUser,
+	oracleSGASharedPoolLibraryCacheReloadRatio,
+	oracleSGASharedPoolLibraryCacheHitRatio,
+	oracleSGASharedPoolDictCacheRatio,
+	oracleSGASharedPoolDictCacheRatio,
+	oracleSGALogBufferSpaceWaits,
+	oracleSGALogAllocRetries,
+	oracleSGAHitRatio,
+	oracleSysstat,
+	oracleSGA,
+	oracleSGAComponents,
+	oracleSGAResizeOperations,
+	oracleRollbackSegments,
+	oracleRedoLogWaits,
+	oracleInstanceStateEvents,
+	oraclePDBStateEvents,
+	oracleCluster,
+	oracleGCInstancePairs,
+	oracleGCBlockServer,
+	oracleGCBlocks,
+	oracleGCMessages,
+	oracleGCInterconnects,
+	oracleSessions,
+	oracleServices,
+	oracleServiceStats,
+	oracleLongOperations,
+	oracleSchedulerJobs,
+	oracleSchedulerRunningJobs,
+	oracleSchedulerJobFailures,
+	oracleLegacyJobs,
+	oracleSchemaHealth,
+	oracleRedoLogs,
+	oracleRedoLogSwitches,
+	oracleLatches,
+	oracleMutexSleeps,
+	oracleEnqueues,
+	oracleIOLatency,
+	oracleIOFileTypes,
+	oracleIOFunctions,
+	oracleInMemory,
+	oracleInMemoryArea,
+	oracleInMemorySegments,
+	oracleConnectionPools,
+	oracleConnectionClasses,
+	oracleParallelServers,
+	oracleParallelQueries,
+	oracleParallelDowngrades,
+}
 
-	// Create a goroutine for each of the base metric groups to collect
-	tablespaceCollections := []oracleMetricGroup{
-		oracleTablespaceMetrics,
-		globalNameTablespaceMetric,
-		dbIDTablespaceMetric,
-		oracleCDBDatafilesOffline,
-		oraclePDBDatafilesOffline,
-		oraclePDBNonWrite,
-	}
-
-	// Create a goroutine for each of the base metric groups to collect
-	baseCollections := []oracleMetricGroup{
-		oracleLockedAccounts,
-		oracleReadWriteMetrics,
-		oraclePgaMetrics,
-		globalNameInstanceMetric,
-		dbIDInstanceMetric,
-		oracleLongRunningQueries,
-		oracleSGAUGATotalMemory,
-		oracleSGASharedPoolLibraryCacheSharableStatement,
-		oracleSGASharedPoolLibraryCacheShareableUser,
-		oracleSGASharedPoolLibraryCacheReloadRatio,
-		oracleSGASharedPoolLibraryCacheHitRatio,
-		oracleSGASharedPoolDictCacheRatio,
-		oracleSGASharedPoolDictCacheRatio,
-		oracleSGALogBufferSpaceWaits,
-		oracleSGALogAllocRetries,
-		oracleSGAHitRatio,
-		oracleSysstat,
-		oracleSGA,
-		oracleRollbackSegments,
-		oracleRedoLogWaits,
+// registeredMetricGroups returns every metric group known to the integration,
+// including the sys metrics groups whose collection depends on SYS_METRICS_SOURCE
+func registeredMetricGroups() []oracleMetricGroup {
+	groups := make([]oracleMetricGroup, 0, len(tablespaceMetricGroups)+len(instanceMetricGroups)+2)
+	groups = append(groups, tablespaceMetricGroups...)
+	groups = append(groups, instanceMetricGroups...)
+	groups = append(groups, oracleSysMetrics, oraclePDBSysMetrics)
+	return groups
+}
+
+// isRegisteredMetricGroup reports whether name matches a registered metric group,
+// using the same case-insensitive comparison as SKIP_METRICS_GROUPS
+func isRegisteredMetricGroup(name string) bool {
+	for _, group := range registeredMetricGroups() {
+		if strings.EqualFold(group.name, name) {
+			return true
+		}
 	}
+	return false
+}
 
+// collect spins off goroutines for each of the metric groups, which
+// send their metrics to the populateMetrics goroutine
+func (mc *metricsCollector) collect() {
 	defer mc.wg.Done()
 
 	var collectorWg sync.WaitGroup
@@ -75,10 +144,10 @@ func (mc *metricsCollector) collect() {
 	// Separate logic is needed to see if we should even collect tablespaces
 	// Collect tablespaces first so the list query completes before other queries are run
 	collectorWg.Add(1)
-	go mc.collectTableSpaces(&collectorWg, metricChan, tablespaceCollections)
+	go mc.collectTableSpaces(&collectorWg, metricChan, tablespaceMetricGroups)
 
-	for _, collection := range baseCollections {
-		if mc.skipGroup(collection.name) {
+	for _, collection := range instanceMetricGroups {
+		if mc.skipGroup(collection) {
 			log.Debug("Metric group %s skipped.", collection.name)
 			continue
 		}
@@ -89,14 +158,14 @@ func (mc *metricsCollector) collect() {
 
 	// Collect PDB metrics only when argument is set to 'PDB' or 'All'
 	collectPDBMetrics := strings.ToLower(args.SysMetricsSource) == "pdb" || strings.ToLower(args.SysMetricsSource) == "all"
-	if collectPDBMetrics {
+	if collectPDBMetrics && !mc.skipGroup(oraclePDBSysMetrics) {
 		collectorWg.Add(1)
 		go oraclePDBSysMetrics.Collect(mc.db, &collectorWg, metricChan)
 	}
 
 	// Collect Sys metrics by default and any value other than 'PDB'
 	collectSysMetrics := strings.ToLower(args.SysMetricsSource) != "pdb"
-	if collectSysMetrics {
+	if collectSysMetrics && !mc.skipGroup(oracleSysMetrics) {
 		collectorWg.Add(1)
 		go oracleSysMetrics.Collect(mc.db, &collectorWg, metricChan)
 	}
@@ -131,7 +200,7 @@ func (mc *metricsCollector) collectTableSpaces(wg *sync.WaitGroup, metricChan ch
 	}
 
 	for _, collection := range tablespaceCollections {
-		if mc.skipGroup(collection.name) {
+		if mc.skipGroup(collection) {
 			log.Debug("Metric group %s skipped.", collection.name)
 			continue
 		}
@@ -141,9 +210,26 @@ func (mc *metricsCollector) collectTableSpaces(wg *sync.WaitGroup, metricChan ch
 	}
 }
 
-func (mc *metricsCollector) skipGroup(metricGroup string) bool {
-	for _, skipMetricsGroup := range mc.skipMetricsGroups {
-		if strings.EqualFold(skipMetricsGroup, metricGroup) {
+// skipGroup reports whether a metric group is excluded from collection, either
+// because it is listed in SKIP_METRICS_GROUPS, because INCLUDE_METRICS_GROUPS
+// is set and does not list it, or because it is an opt-in group missing from
+// ENABLE_METRICS_GROUPS
+func (mc *metricsCollector) skipGroup(metricGroup oracleMetricGroup) bool {
+	if containsGroup(mc.skipMetricsGroups, metricGroup.name) {
+		return true
+	}
+
+	if len(mc.includeMetricsGroups) > 0 {
+		return !containsGroup(mc.includeMetricsGroups, metricGroup.name)
+	}
+
+	return metricGroup.optIn && !containsGroup(mc.enableMetricsGroups, metricGroup.name)
+}
+
+// containsGroup reports whether groups lists name, case-insensitively
+func containsGroup(groups []string, name string) bool {
+	for _, group := range groups {
+		if strings.EqualFold(group, name) {
 			return true
 		}
 	}
@@ -153,9 +239,12 @@ func (mc *metricsCollector) skipGroup(metricGroup string) bool {
 // populateMetrics reads metrics from the metricChan, then populates the correct
 // metric set with the read metric
 func populateMetrics(metricChan <-chan newrelicMetricSender, i *integration.Integration, instanceLookUp map[string]string) {
-	// Create storage maps for tablespace and instance metric sets
+	// Create storage maps for tablespace, instance, cluster, schema and dimensioned metric sets
 	tsMetricSets := make(map[string]*nrmetric.Set)
 	instanceMetricSets := make(map[string]*nrmetric.Set)
+	clusterMetricSets := make(map[string]*nrmetric.Set)
+	schemaMetricSets := make(map[string]*nrmetric.Set)
+	dimensionedMetricSets := make(map[string]*nrmetric.Set)
 
 	for {
 		metricSender, ok := <-metricChan
@@ -163,40 +252,83 @@ func populateMetrics(metricChan <-chan newrelicMetricSender, i *integration.Inte
 			return // return if the channel is closed
 		}
 
+		if metricSender.event != nil {
+			addEvent(metricSender, i, instanceLookUp)
+			continue
+		}
+
 		metric := metricSender.metric
 
+		// Metrics with their own sample type go to a metric set of their entity
+		// per combination of dimensions
+		if _, ok := metricSender.metadata[sampleMetadataKey]; ok && metric != nil {
+			ms := getOrCreateDimensionedMetricSet(metricSender.metadata, dimensionedMetricSets, i, instanceLookUp)
+			if ms == nil {
+				log.Error("Metric %s has no entity to be reported on", metric.name)
+				continue
+			}
+			if err := ms.SetMetric(metric.name, metric.value, metric.metricType); err != nil {
+				log.Error("Failed to set metric %s: %s", metric.name, err)
+			}
+			continue
+		}
+
 		// If the metric belongs to a tablespace, otherwise it belongs to an instance
 		if tsName, ok := metricSender.metadata["tablespace"]; ok { //nolint: nestif
 			ms := getOrCreateMetricSet(tsName, "tablespace", tsMetricSets, i)
 			if err := ms.SetMetric(metric.name, metric.value, metric.metricType); err != nil {
 				log.Error("Failed to set metric %s: %s", metric.name, err)
 			}
+		} else if clusterName, ok := metricSender.metadata["cluster"]; ok {
+			ms := getOrCreateMetricSet(clusterName, "cluster", clusterMetricSets, i)
+			if err := ms.SetMetric(metric.name, metric.value, metric.metricType); err != nil {
+				log.Error("Failed to set metric %s: %s", metric.name, err)
+			}
+		} else if schemaName, ok := metricSender.metadata["schema"]; ok {
+			ms := getOrCreateMetricSet(schemaName, "schema", schemaMetricSets, i)
+			if err := ms.SetMetric(metric.name, metric.value, metric.metricType); err != nil {
+				log.Error("Failed to set metric %s: %s", metric.name, err)
+			}
 		} else if metricSender.isCustom {
-			instanceID := metricSender.metadata["instanceID"]
-			instanceName := func() string {
-				if name, ok := instanceLookUp[instanceID]; ok {
-					return name
-				}
-
-				return instanceID
-			}()
-
 			sampleName := metricSender.metadata["sampleName"]
 
 			for _, row := range metricSender.customMetrics {
-				ms := createCustomMetricSet(sampleName, instanceName, i)
+				entityType, entityName := customRowEntity(row, metricSender.metadata, instanceLookUp)
+				if entityName == "" {
+					log.Warn("Skipping custom query row with an empty %s column", metricSender.metadata["entityNameColumn"])
+					continue
+				}
+
+				ms := createCustomMetricSet(sampleName, entityType, entityName, i)
 				for key, val := range row {
-					sanitized := sanitizeValue(val)
+					sanitized, ok := sanitizeValue(val)
+					if !ok {
+						continue
+					}
+
+					name := key
+					column, transformed := metricSender.customColumns[strings.ToUpper(key)]
+					if transformed {
+						var err error
+						if name, sanitized, err = column.transform(key, sanitized); err != nil {
+							log.Error("Failed to transform column %s with value %v: %s", key, val, err)
+							continue
+						}
+					}
+
 					inferredMetricType := func() nrmetric.SourceType {
+						if transformed && column.Attribute {
+							return nrmetric.ATTRIBUTE
+						}
 						if t, ok := metricSender.metricTypeOverrides[key]; ok {
 							return nrmetric.SourceType(t)
 						}
 						return inferMetricType(sanitized)
 					}()
 
-					err := ms.SetMetric(key, sanitized, inferredMetricType)
+					err := ms.SetMetric(name, sanitized, inferredMetricType)
 					if err != nil {
-						log.Error("Failed to set metric %s with value %v and type %T: %s", key, val, val, err)
+						log.Error("Failed to set metric %s with value %v and type %T: %s", name, val, val, err)
 					}
 				}
 			}
@@ -217,34 +349,199 @@ func populateMetrics(metricChan <-chan newrelicMetricSender, i *integration.Inte
 	}
 }
 
+// customRowInstanceID returns the instance ID held in the instance column of a
+// custom query row, or defaultID when the query has no instance column
+func customRowInstanceID(row map[string]interface{}, instanceColumn string, defaultID string) string {
+	if instanceColumn == "" {
+		return defaultID
+	}
+
+	if id := getInstanceIDString(row[instanceColumn]); id != "" {
+		return id
+	}
+
+	return defaultID
+}
+
+// customRowEntity returns the entity type and name a custom query row is reported on.
+// Rows of instance queries belong to the instance in their instance column, any
+// other entity type is named after the value of the entity name column
+func customRowEntity(row map[string]interface{}, metadata map[string]string, instanceLookUp map[string]string) (string, string) {
+	entityType := metadata["entityType"]
+	if entityType == "" || entityType == defaultCustomEntityType {
+		instanceID := customRowInstanceID(row, metadata["instanceColumn"], metadata["instanceID"])
+		if name, ok := instanceLookUp[instanceID]; ok {
+			return defaultCustomEntityType, name
+		}
+		return defaultCustomEntityType, instanceID
+	}
+
+	value := row[metadata["entityNameColumn"]]
+	if value == nil {
+		return entityType, ""
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return entityType, string(v)
+	default:
+		return entityType, fmt.Sprintf("%v", v)
+	}
+}
+
+// customEntityType normalizes the entity_type of a custom query, accepting both
+// "schema" and "ora-schema". An empty type reports on instances
+func customEntityType(configured string) string {
+	entityType := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(configured)), "ora-")
+	if entityType == "" {
+		return defaultCustomEntityType
+	}
+	return entityType
+}
+
+// customEntityNameColumn returns the result column matching the configured
+// entity_name_column case insensitively, or an empty string if there is none
+func customEntityNameColumn(columns []string, configured string) string {
+	for _, column := range columns {
+		if strings.EqualFold(column, configured) {
+			return column
+		}
+	}
+	return ""
+}
+
+// customInstanceColumn returns the name of the result column that holds the
+// instance ID of each row. The configured column is matched case insensitively
+// and INST_ID is used when none is configured. An empty string is returned when
+// the query has no such column
+func customInstanceColumn(columns []string, configured string) string {
+	wanted := configured
+	if wanted == "" {
+		wanted = defaultCustomInstanceColumn
+	}
+
+	for _, column := range columns {
+		if strings.EqualFold(column, wanted) {
+			return column
+		}
+	}
+
+	if configured != "" {
+		log.Warn("Instance column %s not found in custom query result, attributing rows to the local instance", configured)
+	}
+
+	return ""
+}
+
 func inferMetricType(val interface{}) nrmetric.SourceType {
 	switch val.(type) {
 	case string:
 		return nrmetric.ATTRIBUTE
-	case float32, float64, int, int32, int64:
+	case float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, bool:
 		return nrmetric.GAUGE
 	default:
 		return nrmetric.ATTRIBUTE
 	}
 }
 
-func sanitizeValue(val interface{}) interface{} {
+// sanitizedValue returns a scanned column as sanitizeValue does, or nil when it is dropped
+func sanitizedValue(value interface{}) interface{} {
+	if sanitized, ok := sanitizeValue(value); ok {
+		return sanitized
+	}
+	return nil
+}
+
+// sanitizeValue converts a value scanned from Oracle into a value that can be
+// reported. It returns false when there is nothing to report, either because
+// the value is NULL or because it could not be converted
+func sanitizeValue(val interface{}) (interface{}, bool) {
 	switch v := val.(type) {
-	case string, float32, float64, int, int32, int64:
-		return v
+	case nil:
+		return nil, false
+	case string:
+		return truncateAttribute(v), true
+	case float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, bool:
+		return v, true
 	case godror.Number:
 		num, err := strconv.ParseFloat(string(v), 64)
 		if err != nil {
-			log.Error("Failed to convert %s to a number")
-			return 0
+			log.Error("Failed to convert %s to a number: %s", string(v), err)
+			return nil, false
+		}
+		return num, true
+	case time.Time:
+		return v.Unix(), true
+	case *time.Time:
+		if v == nil {
+			return nil, false
+		}
+		return v.Unix(), true
+	case time.Duration:
+		return v.Seconds(), true
+	case godror.IntervalYM:
+		return (float64(v.Years)*12 + float64(v.Months)) * secondsPerMonth, true
+	case []byte:
+		// RAW and BLOB columns
+		return truncateAttribute(hex.EncodeToString(v)), true
+	case *godror.Lob:
+		if v == nil {
+			return nil, false
 		}
-		return num
+		return readLob(v)
+	case sql.NullString:
+		return sanitizeNullable(v.Valid, v.String)
+	case sql.NullFloat64:
+		return sanitizeNullable(v.Valid, v.Float64)
+	case sql.NullInt64:
+		return sanitizeNullable(v.Valid, v.Int64)
+	case sql.NullInt32:
+		return sanitizeNullable(v.Valid, v.Int32)
+	case sql.NullBool:
+		return sanitizeNullable(v.Valid, v.Bool)
+	case sql.NullTime:
+		return sanitizeNullable(v.Valid, v.Time)
 	default:
 		log.Warn("Unknown metric type %T. Falling back to sending as string", val)
-		return fmt.Sprintf("%v", v)
+		return truncateAttribute(fmt.Sprintf("%v", v)), true
 	}
 }
 
+func sanitizeNullable(valid bool, val interface{}) (interface{}, bool) {
+	if !valid {
+		return nil, false
+	}
+	return sanitizeValue(val)
+}
+
+// truncateAttribute truncates strings, such as CLOBs, to the longest attribute value accepted by New Relic
+func truncateAttribute(s string) string {
+	if len(s) <= maxAttributeLength {
+		return s
+	}
+
+	// Avoid cutting a multi-byte character in half
+	end := maxAttributeLength
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
+
+// readLob reads the beginning of a LOB scanned as a reader
+func readLob(lob *godror.Lob) (interface{}, bool) {
+	contents, err := io.ReadAll(io.LimitReader(lob, maxAttributeLength))
+	if err != nil {
+		log.Error("Failed to read LOB: %s", err)
+		return nil, false
+	}
+
+	if lob.IsClob {
+		return truncateAttribute(string(contents)), true
+	}
+	return truncateAttribute(hex.EncodeToString(contents)), true
+}
+
 // getOrCreateMetricSet either retrieves a metric set from a map or creates the metric set
 // and inserts it into the map.
 func getOrCreateMetricSet(entityIdentifier string, entityType string, m map[string]*nrmetric.Set, i *integration.Integration) *nrmetric.Set {
@@ -255,15 +552,7 @@ func getOrCreateMetricSet(entityIdentifier string, entityType string, m map[stri
 	}
 
 	// If the metric set doesn't exist, get the entity for it and create a new metric set
-	endpointIDAttr := integration.IDAttribute{Key: "endpoint", Value: fmt.Sprintf("%s:%s", args.Hostname, args.Port)}
-	serviceIDAttr := integration.IDAttribute{Key: "serviceName", Value: args.ServiceName}
-	e, _ := i.EntityReportedVia( // can't error if both name and namespace are defined
-		fmt.Sprintf("%s:%s", args.Hostname, args.Port),
-		entityIdentifier,
-		fmt.Sprintf("ora-%s", entityType),
-		endpointIDAttr,
-		serviceIDAttr,
-	)
+	e := reportedEntity(entityIdentifier, entityType, i)
 
 	var newSet *nrmetric.Set
 	switch entityType {
@@ -271,6 +560,10 @@ func getOrCreateMetricSet(entityIdentifier string, entityType string, m map[stri
 		newSet = e.NewMetricSet("OracleDatabaseSample", attribute.Attr("entityName", "ora-instance:"+entityIdentifier), attribute.Attr("displayName", entityIdentifier))
 	case "tablespace":
 		newSet = e.NewMetricSet("OracleTablespaceSample", attribute.Attr("entityName", "ora-tablespace:"+entityIdentifier), attribute.Attr("displayName", entityIdentifier))
+	case "cluster":
+		newSet = e.NewMetricSet("OracleClusterSample", attribute.Attr("entityName", "ora-cluster:"+entityIdentifier), attribute.Attr("displayName", entityIdentifier))
+	case "schema":
+		newSet = e.NewMetricSet("OracleSchemaSample", attribute.Attr("entityName", "ora-schema:"+entityIdentifier), attribute.Attr("displayName", entityIdentifier))
 	default:
 		log.Error("Unreachable code")
 		os.Exit(1)
@@ -282,18 +575,108 @@ func getOrCreateMetricSet(entityIdentifier string, entityType string, m map[stri
 	return newSet
 }
 
-func createCustomMetricSet(sampleName string, instanceID string, i *integration.Integration) *nrmetric.Set {
+// metadataEntity returns the entity type and name a metric or event is reported on,
+// according to its metadata
+func metadataEntity(metadata map[string]string, instanceLookUp map[string]string) (string, string, bool) {
+	if tsName, ok := metadata["tablespace"]; ok {
+		return "tablespace", tsName, true
+	}
+	if clusterName, ok := metadata["cluster"]; ok {
+		return "cluster", clusterName, true
+	}
+	if serviceName, ok := metadata["service"]; ok {
+		return "service", serviceName, true
+	}
+	if schemaName, ok := metadata["schema"]; ok {
+		return "schema", schemaName, true
+	}
+	if instanceID, ok := metadata["instanceID"]; ok {
+		return "instance", instanceName(instanceID, instanceLookUp), true
+	}
+	return "", "", false
+}
+
+// instanceName returns the name of the instance with the given ID, or the ID
+// itself when it is unknown
+func instanceName(instanceID string, instanceLookUp map[string]string) string {
+	if name, ok := instanceLookUp[instanceID]; ok {
+		return name
+	}
+	return instanceID
+}
+
+// getOrCreateDimensionedMetricSet returns the metric set of the sample type in the
+// metadata for its entity and dimensions, creating it if it doesn't exist yet.
+// Dimensions are the metadata entries prefixed with dimension., reported as attributes
+func getOrCreateDimensionedMetricSet(metadata map[string]string, m map[string]*nrmetric.Set, i *integration.Integration, instanceLookUp map[string]string) *nrmetric.Set {
+	entityType, entityName, ok := metadataEntity(metadata, instanceLookUp)
+	if !ok {
+		return nil
+	}
+
+	var dimensions []string
+	for key := range metadata {
+		if strings.HasPrefix(key, dimensionMetadataPrefix) {
+			dimensions = append(dimensions, key)
+		}
+	}
+	sort.Strings(dimensions)
+
+	setKey := fmt.Sprintf("%s:%s|%s", entityType, entityName, metadata[sampleMetadataKey])
+	attributes := []attribute.Attribute{
+		attribute.Attr("entityName", fmt.Sprintf("ora-%s:%s", entityType, entityName)),
+		attribute.Attr("displayName", entityName),
+	}
+	for _, key := range dimensions {
+		name := strings.TrimPrefix(key, dimensionMetadataPrefix)
+		value := metadata[key]
+		if name == instanceDimension || name == remoteInstanceDimension {
+			value = instanceName(value, instanceLookUp)
+		}
+		setKey += fmt.Sprintf("|%s=%s", name, value)
+		attributes = append(attributes, attribute.Attr(name, value))
+	}
+
+	if set, ok := m[setKey]; ok {
+		return set
+	}
+
+	set := reportedEntity(entityName, entityType, i).NewMetricSet(metadata[sampleMetadataKey], attributes...)
+	m[setKey] = set
+	return set
+}
+
+// reportedEntity returns the ora-<entityType> entity named entityIdentifier,
+// creating it if it doesn't exist yet
+func reportedEntity(entityIdentifier string, entityType string, i *integration.Integration) *integration.Entity {
 	endpointIDAttr := integration.IDAttribute{Key: "endpoint", Value: fmt.Sprintf("%s:%s", args.Hostname, args.Port)}
 	serviceIDAttr := integration.IDAttribute{Key: "serviceName", Value: args.ServiceName}
 	e, _ := i.EntityReportedVia( // can't error if both name and namespace are defined
 		fmt.Sprintf("%s:%s", args.Hostname, args.Port),
-		instanceID,
-		"ora-instance",
+		entityIdentifier,
+		fmt.Sprintf("ora-%s", entityType),
 		endpointIDAttr,
 		serviceIDAttr,
 	)
+	return e
+}
+
+// addEvent adds the event of a sender to the entity in its metadata
+func addEvent(metricSender newrelicMetricSender, i *integration.Integration, instanceLookUp map[string]string) {
+	entityType, entityName, ok := metadataEntity(metricSender.metadata, instanceLookUp)
+	if !ok {
+		log.Error("Event %s has no entity to be reported on", metricSender.event.Summary)
+		return
+	}
+
+	if err := reportedEntity(entityName, entityType, i).AddEvent(metricSender.event); err != nil {
+		log.Error("Failed to add event %s: %s", metricSender.event.Summary, err)
+	}
+}
 
-	return e.NewMetricSet(sampleName, attribute.Attr("entityName", "ora-instance:"+instanceID), attribute.Attr("displayName", instanceID))
+func createCustomMetricSet(sampleName string, entityType string, entityName string, i *integration.Integration) *nrmetric.Set {
+	e := reportedEntity(entityName, entityType, i)
+	return e.NewMetricSet(sampleName, attribute.Attr("entityName", fmt.Sprintf("ora-%s:%s", entityType, entityName)), attribute.Attr("displayName", entityName))
 }
 
 // PopulateCustomMetricsFromFile collects metrics defined by a custom config file
@@ -316,6 +699,17 @@ func PopulateCustomMetricsFromFile(db database.DBWrapper, wg *sync.WaitGroup, me
 	const customQueryCount = 10
 	sem := make(chan struct{}, customQueryCount)
 	for _, config := range customYAML.Queries {
+		if config.MinVersion != "" || config.MaxVersion != "" {
+			if oracleVersion == "" || !customQueryVersionMatches(config, oracleVersion) {
+				log.Debug("Custom query %s does not apply to database version %s, skipping", formatQueryForLogging(config.Query), oracleVersion)
+				continue
+			}
+		}
+
+		if !customQueryDue(config) {
+			continue
+		}
+
 		sem <- struct{}{}
 		wg.Add(1)
 		go func(cfg customMetricsConfig) {
@@ -352,7 +746,32 @@ func CollectCustomConfig(db database.DBWrapper, metricChan chan<- newrelicMetric
 		}
 	}
 
-	rows, err := db.Queryx(cfg.Query)
+	if err := checkCustomQuery(cfg.Query); err != nil {
+		log.Error("Refusing to run custom query %s: %s", formatQueryForLogging(cfg.Query), err)
+		return
+	}
+
+	bindArgs, err := customQueryBindArgs(cfg)
+	if err != nil {
+		log.Error("Failed to bind parameters of custom query %s: %s", formatQueryForLogging(cfg.Query), err)
+		return
+	}
+
+	ctx := context.Background()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.Timeout))
+		defer cancel()
+	}
+
+	tx, err := db.BeginReadOnly(ctx)
+	if err != nil {
+		log.Error("Failed to start read-only transaction for query %s: %s", formatQueryForLogging(cfg.Query), err)
+		return
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryxContext(ctx, cfg.Query, bindArgs...)
 	if err != nil {
 		log.Error("Could not execute database query %s: %s", formatQueryForLogging(cfg.Query), err.Error())
 		return
@@ -362,6 +781,26 @@ func CollectCustomConfig(db database.DBWrapper, metricChan chan<- newrelicMetric
 		rows.Close()
 	}()
 
+	columns, err := rows.Columns()
+	if err != nil {
+		log.Error("Failed to retrieve columns of custom query %s: %s", formatQueryForLogging(cfg.Query), err)
+		return
+	}
+
+	entityType := customEntityType(cfg.EntityType)
+	entityNameColumn := ""
+	if entityType != defaultCustomEntityType {
+		if cfg.EntityNameColumn == "" {
+			log.Error("Custom query %s reports on ora-%s entities but has no entity_name_column", formatQueryForLogging(cfg.Query), entityType)
+			return
+		}
+		entityNameColumn = customEntityNameColumn(columns, cfg.EntityNameColumn)
+		if entityNameColumn == "" {
+			log.Error("Entity name column %s not found in custom query result %s", cfg.EntityNameColumn, formatQueryForLogging(cfg.Query))
+			return
+		}
+	}
+
 	sampleName := func() string {
 		if cfg.SampleName == "" {
 			return defaultCustomSampleType
@@ -372,14 +811,23 @@ func CollectCustomConfig(db database.DBWrapper, metricChan chan<- newrelicMetric
 	sender := newrelicMetricSender{
 		isCustom: true,
 		metadata: map[string]string{
-			"instanceID": instanceID,
-			"sampleName": sampleName,
+			"instanceID":       instanceID,
+			"instanceColumn":   customInstanceColumn(columns, cfg.InstanceColumn),
+			"sampleName":       sampleName,
+			"entityType":       entityType,
+			"entityNameColumn": entityNameColumn,
 		},
 		metricTypeOverrides: cfg.MetricTypes,
+		customColumns:       customColumnsByName(cfg.Columns),
 		customMetrics:       make([]map[string]interface{}, 0),
 	}
 
 	for rows.Next() {
+		if cfg.MaxRows > 0 && len(sender.customMetrics) >= cfg.MaxRows {
+			log.Warn("Custom query %s returned more than %d rows, ignoring the rest", formatQueryForLogging(cfg.Query), cfg.MaxRows)
+			break
+		}
+
 		row := make(map[string]interface{})
 		err := rows.MapScan(row)
 		if err != nil {
@@ -390,6 +838,12 @@ func CollectCustomConfig(db database.DBWrapper, metricChan chan<- newrelicMetric
 		sender.customMetrics = append(sender.customMetrics, row)
 	}
 
+	if err := rows.Err(); err != nil {
+		log.Error("Failed to read the results of custom query %s: %s", formatQueryForLogging(cfg.Query), err)
+		return
+	}
+
+	markCustomQueryRun(cfg)
 	metricChan <- sender
 }
 
@@ -416,7 +870,17 @@ type customMetricsYAML struct {
 }
 
 type customMetricsConfig struct {
-	Query       string                `yaml:"query"`
-	MetricTypes map[string]metricType `yaml:"metric_types"`
-	SampleName  string                `yaml:"sample_name"`
+	Query            string                          `yaml:"query"`
+	MetricTypes      map[string]metricType           `yaml:"metric_types"`
+	SampleName       string                          `yaml:"sample_name"`
+	InstanceColumn   string                          `yaml:"instance_column"`
+	EntityType       string                          `yaml:"entity_type"`
+	EntityNameColumn string                          `yaml:"entity_name_column"`
+	Parameters       map[string]customQueryParameter `yaml:"parameters"`
+	Interval         customDuration                  `yaml:"interval"`
+	Timeout          customDuration                  `yaml:"timeout"`
+	MaxRows          int                             `yaml:"max_rows"`
+	MinVersion       string                          `yaml:"min_version"`
+	MaxVersion       string                          `yaml:"max_version"`
+	Columns          map[string]customColumn         `yaml:"columns"`
 }
@@ -764,3 +764,90 @@ func TestOracleLockedAccountsMetrics(t *testing.T) {
 		})
 	}
 }
+
+func Test_metricEnabled(t *testing.T) {
+	defaultMetric := &oracleMetric{name: "memory.pgaMaxSizeInBytes", defaultMetric: true}
+	extendedMetric := &oracleMetric{name: "memory.pgaInUseInBytes", defaultMetric: false}
+	perTransaction := &oracleMetric{name: "db.logonsPerTransaction", defaultMetric: false}
+
+	testCases := []struct {
+		name     string
+		extended bool
+		include  []string
+		exclude  []string
+		metric   *oracleMetric
+		expected bool
+	}{
+		{"default metric", false, nil, nil, defaultMetric, true},
+		{"extended metric disabled", false, nil, nil, extendedMetric, false},
+		{"extended metric enabled", true, nil, nil, extendedMetric, true},
+		{"included by exact name", false, []string{"memory.pgaInUseInBytes"}, nil, extendedMetric, true},
+		{"included by glob", false, []string{"db.*PerTransaction"}, nil, perTransaction, true},
+		{"glob does not match", false, []string{"db.*PerTransaction"}, nil, extendedMetric, false},
+		{"excluded default metric", false, nil, []string{"memory.*"}, defaultMetric, false},
+		{"exclude wins over include", true, []string{"memory.*"}, []string{"memory.pgaInUse*"}, extendedMetric, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args = argumentList{ExtendedMetrics: tc.extended}
+			includeMetricPatterns, excludeMetricPatterns = tc.include, tc.exclude
+			defer func() {
+				args = argumentList{}
+				includeMetricPatterns, excludeMetricPatterns = nil, nil
+			}()
+
+			if got := metricEnabled(tc.metric); got != tc.expected {
+				t.Errorf("Expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestOraclePgaMetrics_IncludeExcludeMetrics(t *testing.T) {
+	includeMetricPatterns = []string{"memory.pga*"}
+	excludeMetricPatterns = []string{"memory.pgaFreeable*"}
+	defer func() { includeMetricPatterns, excludeMetricPatterns = nil, nil }()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+
+	mock.ExpectQuery(".*").WillReturnRows(
+		sqlmock.NewRows([]string{"INST_ID", "NAME", "VALUE"}).
+			AddRow(1, "total PGA inuse", 1).
+			AddRow(1, "total freeable PGA memory", 2),
+	)
+
+	var wg sync.WaitGroup
+	metricChan := make(chan newrelicMetricSender, 10)
+
+	dbWrapper := database.NewDBWrapper(sqlx.NewDb(db, "sqlmock"))
+	wg.Add(1)
+	go oraclePgaMetrics.Collect(dbWrapper, &wg, metricChan)
+	go func() {
+		wg.Wait()
+		close(metricChan)
+	}()
+
+	var generatedMetrics []newrelicMetricSender
+	for newMetric := range metricChan {
+		generatedMetrics = append(generatedMetrics, newMetric)
+	}
+
+	expectedMetrics := []newrelicMetricSender{
+		{
+			metric: &newrelicMetric{
+				name:       "memory.pgaInUseInBytes",
+				value:      float64(1),
+				metricType: metric.GAUGE,
+			},
+			metadata: map[string]string{"instanceID": "1"},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedMetrics, generatedMetrics) {
+		t.Errorf("failed to get expected metric: %s", pretty.Diff(expectedMetrics, generatedMetrics))
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"database/sql"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/godror/godror"
 	"github.com/jmoiron/sqlx"
 	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
 	"github.com/newrelic/infra-integrations-sdk/v3/integration"
@@ -398,8 +403,10 @@ func Test_PopulateMetrics_FromCustomQueryFile(t *testing.T) {
 		sqlmock.NewRows(columns).AddRow("1"),
 	)
 
-	// queries from query file
+	// queries from query file, each run in its own read-only transaction
 	columns = []string{"val1", "val2"}
+	mock.ExpectBegin()
+	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT.*FROM numbers.*").WillReturnRows(
 		sqlmock.NewRows(columns).AddRow("one", "two"),
 	)
@@ -407,6 +414,8 @@ func Test_PopulateMetrics_FromCustomQueryFile(t *testing.T) {
 	mock.ExpectQuery("SELECT.*FROM somewhere.*").WillReturnRows(
 		sqlmock.NewRows(columns).AddRow("something", "otherthing"),
 	)
+	mock.ExpectRollback()
+	mock.ExpectRollback()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
 	dbWrapper := database.NewDBWrapper(sqlxDB)
@@ -471,3 +480,276 @@ func Test_CollectMetrics_SkippedList(t *testing.T) {
 		t.Errorf("Metrics group should be excluded from collection: %s", err)
 	}
 }
+
+func Test_skipGroup(t *testing.T) {
+	sga := oracleMetricGroup{name: "sga"}
+	latches := oracleMetricGroup{name: "latches", optIn: true}
+
+	testCases := []struct {
+		name     string
+		skip     []string
+		include  []string
+		enable   []string
+		group    oracleMetricGroup
+		expected bool
+	}{
+		{"no filters", nil, nil, nil, sga, false},
+		{"skipped", []string{"SGA"}, nil, nil, sga, true},
+		{"included", nil, []string{"sga", "sys_metrics"}, nil, sga, false},
+		{"not included", nil, []string{"sys_metrics"}, nil, sga, true},
+		{"skip wins over include", []string{"sga"}, []string{"sga"}, nil, sga, true},
+		{"opt-in not enabled", nil, nil, nil, latches, true},
+		{"opt-in enabled", nil, nil, []string{"LATCHES"}, latches, false},
+		{"opt-in included", nil, []string{"latches"}, nil, latches, false},
+		{"opt-in enabled but not included", nil, []string{"sga"}, []string{"latches"}, latches, true},
+		{"skip wins over enable", []string{"latches"}, nil, []string{"latches"}, latches, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mc := metricsCollector{skipMetricsGroups: tc.skip, includeMetricsGroups: tc.include, enableMetricsGroups: tc.enable}
+			if got := mc.skipGroup(tc.group); got != tc.expected {
+				t.Errorf("Expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPopulateMetrics_CustomRowsPerInstance(t *testing.T) {
+	args = argumentList{
+		Hostname:    "testhost",
+		Port:        "1234",
+		ServiceName: "testServiceName",
+	}
+	defer func() { args = argumentList{} }()
+
+	testCases := []struct {
+		name           string
+		instanceColumn string
+		expectedJSON   string
+	}{
+		{
+			name:           "rows routed by instance column",
+			instanceColumn: "INST_ID",
+			expectedJSON:   `{"name":"oracletest","protocol_version":"3","integration_version":"0.0.1","data":[{"entity":{"name":"one","type":"ora-instance","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[{"INST_ID":1,"VALUE":10,"displayName":"one","entityName":"ora-instance:one","event_type":"OracleCustomSample","reportingEndpoint":"testhost:1234"}],"inventory":{},"events":[]},{"entity":{"name":"two","type":"ora-instance","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[{"INST_ID":2,"VALUE":20,"displayName":"two","entityName":"ora-instance:two","event_type":"OracleCustomSample","reportingEndpoint":"testhost:1234"}],"inventory":{},"events":[]}]}`,
+		},
+		{
+			name:           "rows attributed to the local instance",
+			instanceColumn: "",
+			expectedJSON:   `{"name":"oracletest","protocol_version":"3","integration_version":"0.0.1","data":[{"entity":{"name":"one","type":"ora-instance","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[{"INST_ID":1,"VALUE":10,"displayName":"one","entityName":"ora-instance:one","event_type":"OracleCustomSample","reportingEndpoint":"testhost:1234"},{"INST_ID":2,"VALUE":20,"displayName":"one","entityName":"ora-instance:one","event_type":"OracleCustomSample","reportingEndpoint":"testhost:1234"}],"inventory":{},"events":[]}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			i, _ := integration.New("oracletest", "0.0.1")
+			metricChan := make(chan newrelicMetricSender, 1)
+			metricChan <- newrelicMetricSender{
+				isCustom: true,
+				metadata: map[string]string{
+					"instanceID":     "1",
+					"instanceColumn": tc.instanceColumn,
+					"sampleName":     defaultCustomSampleType,
+				},
+				customMetrics: []map[string]interface{}{
+					{"INST_ID": int64(1), "VALUE": int64(10)},
+					{"INST_ID": int64(2), "VALUE": int64(20)},
+				},
+			}
+			close(metricChan)
+
+			populateMetrics(metricChan, i, map[string]string{"1": "one", "2": "two"})
+
+			marshalled, err := i.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(marshalled) != tc.expectedJSON {
+				t.Errorf("Expected %s, got %s", tc.expectedJSON, marshalled)
+			}
+		})
+	}
+}
+
+func Test_customInstanceColumn(t *testing.T) {
+	columns := []string{"INST_ID", "INSTANCE_NUMBER", "VALUE"}
+
+	testCases := []struct {
+		configured string
+		expected   string
+	}{
+		{"", "INST_ID"},
+		{"instance_number", "INSTANCE_NUMBER"},
+		{"missing", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := customInstanceColumn(columns, tc.configured); got != tc.expected {
+			t.Errorf("Expected column %q for %q, got %q", tc.expected, tc.configured, got)
+		}
+	}
+
+	if got := customInstanceColumn([]string{"VALUE"}, ""); got != "" {
+		t.Errorf("Expected no instance column, got %q", got)
+	}
+}
+
+func TestCollectCustomConfig_EntityType(t *testing.T) {
+	args = argumentList{
+		Hostname:    "testhost",
+		Port:        "1234",
+		ServiceName: "testServiceName",
+	}
+	defer func() { args = argumentList{} }()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("SELECT.*FROM v\\$instance").WillReturnRows(
+		sqlmock.NewRows([]string{"INSTANCE_NUMBER"}).AddRow("1"),
+	)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT.*FROM dba_objects.*").WillReturnRows(
+		sqlmock.NewRows([]string{"OWNER", "OBJECTS"}).AddRow("HR", 10).AddRow("SALES", 20),
+	)
+	mock.ExpectRollback()
+
+	dbWrapper := database.NewDBWrapper(sqlx.NewDb(db, "sqlmock"))
+	metricChan := make(chan newrelicMetricSender, 1)
+	CollectCustomConfig(dbWrapper, metricChan, customMetricsConfig{
+		Query:            "SELECT owner, count(*) AS objects FROM dba_objects GROUP BY owner",
+		SampleName:       "OracleSchemaSample",
+		EntityType:       "ora-schema",
+		EntityNameColumn: "owner",
+	})
+	close(metricChan)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	i, _ := integration.New("oracletest", "0.0.1")
+	populateMetrics(metricChan, i, map[string]string{"1": "one"})
+
+	marshalled, err := i.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedJSON := `{"name":"oracletest","protocol_version":"3","integration_version":"0.0.1","data":[{"entity":{"name":"HR","type":"ora-schema","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[{"OBJECTS":10,"OWNER":"HR","displayName":"HR","entityName":"ora-schema:HR","event_type":"OracleSchemaSample","reportingEndpoint":"testhost:1234"}],"inventory":{},"events":[]},{"entity":{"name":"SALES","type":"ora-schema","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[{"OBJECTS":20,"OWNER":"SALES","displayName":"SALES","entityName":"ora-schema:SALES","event_type":"OracleSchemaSample","reportingEndpoint":"testhost:1234"}],"inventory":{},"events":[]}]}`
+	if string(marshalled) != expectedJSON {
+		t.Errorf("Expected %s, got %s", expectedJSON, marshalled)
+	}
+}
+
+func TestCollectCustomConfig_MissingEntityNameColumn(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("SELECT.*FROM v\\$instance").WillReturnRows(
+		sqlmock.NewRows([]string{"INSTANCE_NUMBER"}).AddRow("1"),
+	)
+	mock.ExpectQuery("SELECT.*FROM dba_objects.*").WillReturnRows(
+		sqlmock.NewRows([]string{"OWNER", "OBJECTS"}).AddRow("HR", 10),
+	)
+
+	dbWrapper := database.NewDBWrapper(sqlx.NewDb(db, "sqlmock"))
+	metricChan := make(chan newrelicMetricSender, 1)
+	CollectCustomConfig(dbWrapper, metricChan, customMetricsConfig{
+		Query:            "SELECT owner, count(*) AS objects FROM dba_objects GROUP BY owner",
+		EntityType:       "pdb",
+		EntityNameColumn: "PDB_NAME",
+	})
+	close(metricChan)
+
+	if _, ok := <-metricChan; ok {
+		t.Error("Expected no metrics when the entity name column is missing")
+	}
+}
+
+func Test_customEntityType(t *testing.T) {
+	testCases := map[string]string{
+		"":               "instance",
+		"ora-tablespace": "tablespace",
+		"PDB":            "pdb",
+		"ora-schema":     "schema",
+		"job":            "job",
+	}
+
+	for configured, expected := range testCases {
+		if got := customEntityType(configured); got != expected {
+			t.Errorf("Expected entity type %q for %q, got %q", expected, configured, got)
+		}
+	}
+}
+
+func Test_sanitizeValue(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	longClob := strings.Repeat("a", maxAttributeLength+10)
+	multiByte := strings.Repeat("a", maxAttributeLength-1) + "é"
+
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+		ok       bool
+	}{
+		{"NULL", nil, nil, false},
+		{"string", "ONLINE", "ONLINE", true},
+		{"float", 1.5, 1.5, true},
+		{"int64", int64(7), int64(7), true},
+		{"bool", true, true, true},
+		{"number", godror.Number("12.25"), 12.25, true},
+		{"unparseable number", godror.Number("12,25"), nil, false},
+		{"timestamp", timestamp, timestamp.Unix(), true},
+		{"timestamp pointer", &timestamp, timestamp.Unix(), true},
+		{"nil timestamp pointer", (*time.Time)(nil), nil, false},
+		{"interval day to second", 90 * time.Second, float64(90), true},
+		{"interval year to month", godror.IntervalYM{Years: 1, Months: 6}, 18 * secondsPerMonth, true},
+		{"raw", []byte{0xde, 0xad}, "dead", true},
+		{"clob truncated", longClob, longClob[:maxAttributeLength], true},
+		{"truncation keeps characters whole", multiByte, multiByte[:maxAttributeLength-1], true},
+		{"clob reader", &godror.Lob{Reader: strings.NewReader("text"), IsClob: true}, "text", true},
+		{"blob reader", &godror.Lob{Reader: strings.NewReader("ab")}, "6162", true},
+		{"valid null float", sql.NullFloat64{Float64: 2, Valid: true}, float64(2), true},
+		{"invalid null float", sql.NullFloat64{}, nil, false},
+		{"valid null string", sql.NullString{String: "x", Valid: true}, "x", true},
+		{"invalid null int", sql.NullInt64{}, nil, false},
+		{"valid null time", sql.NullTime{Time: timestamp, Valid: true}, timestamp.Unix(), true},
+		{"unknown type", struct{ A int }{1}, "{1}", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, ok := sanitizeValue(tc.value)
+			if ok != tc.ok || !reflect.DeepEqual(value, tc.expected) {
+				t.Errorf("Expected %v (%T), %t, got %v (%T), %t", tc.expected, tc.expected, tc.ok, value, value, ok)
+			}
+		})
+	}
+}
+
+func Test_inferMetricType(t *testing.T) {
+	testCases := []struct {
+		value    interface{}
+		expected metric.SourceType
+	}{
+		{"ONLINE", metric.ATTRIBUTE},
+		{1.5, metric.GAUGE},
+		{int64(1), metric.GAUGE},
+		{uint32(1), metric.GAUGE},
+		{true, metric.GAUGE},
+		{struct{}{}, metric.ATTRIBUTE},
+	}
+
+	for _, tc := range testCases {
+		if got := inferMetricType(tc.value); got != tc.expected {
+			t.Errorf("Expected %s for %v (%T), got %s", tc.expected, tc.value, tc.value, got)
+		}
+	}
+}
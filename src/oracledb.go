@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/godror/godror"
 	"github.com/godror/godror/dsn"
@@ -30,13 +32,29 @@ type argumentList struct {
 	Port                  string `default:"1521" help:"The OracleDB connection port"`
 	ExtendedMetrics       bool   `default:"false" help:"Enable extended metrics"`
 	SkipMetricsGroups     string `default:"" help:"JSON Array of of metric groups that will be skipped of collection."`
+	IncludeMetricsGroups  string `default:"" help:"JSON Array of metric groups to collect. If empty will collect all metric groups not skipped."`
+	EnableMetricsGroups   string `default:"" help:"JSON Array of opt-in metric groups to collect on top of the default ones, such as latches or io_latency"`
+	IncludeMetrics        string `default:"" help:"JSON Array of glob patterns of metric names to collect even if they are not default metrics, e.g. db.*PerTransaction"`
+	ExcludeMetrics        string `default:"" help:"JSON Array of glob patterns of metric names that will not be collected"`
 	MaxOpenConnections    int    `default:"5" help:"Maximum number of connections opened by the integration"`
+	SessionsTopN          int    `default:"10" help:"Number of values of each dimension reported by the sessions group, sessions with any other value are reported as 'other'. Zero reports every value"`
+	SessionIdleMinutes    int    `default:"30" help:"Minutes without a call after which an inactive user session is counted as idle"`
+	SlowMetricsInterval   string `default:"1h" help:"Minimum time between two collections of the slow metric groups, such as schema_health"`
+	SchemaHealthExclude   string `default:"" help:"JSON Array of schemas excluded from the schema_health group, on top of the schemas maintained by Oracle which are always excluded"`
+	SegmentsTopN          int    `default:"10" help:"Number of segments reported by the segments group, both by size and by growth since the last run, and of In-Memory segments reported per instance by bytes not populated. Zero reports every segment"`
+	SegmentGrowthFromAWR  bool   `default:"false" help:"Read segment growth from dba_hist_seg_stat. Requires the Oracle Diagnostics Pack license"`
+	ContentionTopN        int    `default:"10" help:"Number of latches, mutex locations and enqueue types reported per instance by the contention groups, ranked by their rate since the last run. Zero reports all of them"`
 	ConnectionString      string `default:"" help:"An advanced connection string. Takes precedence over host, port, and service name"`
 	CustomMetricsQuery    string `default:"" help:"A SQL query to collect custom metrics. Must have the columns metric_name, metric_type, and metric_value. Additional columns are added as attributes"`
 	CustomMetricsConfig   string `default:"" help:"YAML configuration file with one or more custom SQL queries to collect"`
+	CustomQueryAllowlist  string `default:"" help:"JSON Array of SHA-256 hashes of the custom queries allowed to run. If empty any read-only custom query is allowed"`
 	DisableConnectionPool bool   `default:"false" help:"Disables connection pooling. It may make the integration run slower but may reduce issues with not being able to execute queries due to ORA-24459 (failure to get new connection)"`
 	ShowVersion           bool   `default:"false" help:"Print build information and exit"`
 	SysMetricsSource      string `default:"" help:"Default setting work for Standalone and Multitenant with CDB access only. For application container metrics set to 'PDB', or 'All' for CDB & PDB containers"`
+	Validate              bool   `default:"false" help:"Validate the arguments and the custom metrics config file, report every problem found and exit without connecting to the database"`
+	ListMetricGroups      bool   `default:"false" help:"Print every metric group with its query, metrics and entity type, and exit"`
+	DescribeGroup         string `default:"" help:"Print the query, metrics and entity type of the named metric group, and exit"`
+	MetricGroupsFormat    string `default:"text" help:"Output format for list_metric_groups and describe_group: 'text', 'markdown' or 'csv'"`
 }
 
 const (
@@ -44,11 +62,18 @@ const (
 )
 
 var (
-	args                argumentList
-	tablespaceWhiteList []string
-	integrationVersion  = "0.0.0"
-	gitCommit           = ""
-	buildDate           = ""
+	args                  argumentList
+	tablespaceWhiteList   []string
+	includeMetricPatterns []string
+	excludeMetricPatterns []string
+	slowMetricsInterval   time.Duration
+	// schemaHealthExclude are the schemas excluded from schema_health along with the Oracle maintained ones
+	schemaHealthExclude []string
+	// oracleVersion is the version of the instance the integration is connected to
+	oracleVersion      string
+	integrationVersion = "0.0.0"
+	gitCommit          = ""
+	buildDate          = ""
 )
 
 func main() {
@@ -68,6 +93,20 @@ func main() {
 		os.Exit(0)
 	}
 
+	if args.Validate {
+		os.Exit(runValidation())
+	}
+
+	if args.ListMetricGroups {
+		exitOnErr(listMetricGroups(os.Stdout, args.MetricGroupsFormat))
+		os.Exit(0)
+	}
+
+	if args.DescribeGroup != "" {
+		exitOnErr(describeMetricGroup(os.Stdout, args.MetricGroupsFormat, args.DescribeGroup))
+		os.Exit(0)
+	}
+
 	// parse tablespace whitelist
 	err = parseTablespaceWhitelist()
 	exitOnErr(err)
@@ -75,6 +114,24 @@ func main() {
 	skipMetricsGroups, err := parseSkipMetricsGroups()
 	exitOnErr(err)
 
+	includeMetricsGroups, err := parseIncludeMetricsGroups()
+	exitOnErr(err)
+
+	enableMetricsGroups, err := parseEnableMetricsGroups()
+	exitOnErr(err)
+
+	err = parseMetricFilters()
+	exitOnErr(err)
+
+	err = parseCustomQueryAllowlist()
+	exitOnErr(err)
+
+	err = parseSlowMetricsInterval()
+	exitOnErr(err)
+
+	err = parseSchemaHealthExclude()
+	exitOnErr(err)
+
 	db, err := sqlx.Open("godror", getConnectionString())
 	exitOnErr(err)
 	db.SetMaxOpenConns(args.MaxOpenConnections)
@@ -88,6 +145,9 @@ func main() {
 	err = db.Ping()
 	exitOnErr(err)
 
+	stateStore, err = openStateStore()
+	exitOnErr(err)
+
 	var populaterWg sync.WaitGroup
 
 	dbWrapper := database.NewDBWrapper(db)
@@ -95,16 +155,22 @@ func main() {
 	instanceLookUp, err := createInstanceIDLookup(dbWrapper)
 	exitOnErr(err)
 
+	if oracleVersion, err = databaseVersion(dbWrapper); err != nil {
+		log.Warn("Failed to determine the database version, assuming the oldest supported release and skipping custom queries with min_version or max_version: %s", err)
+	}
+
 	if args.HasMetrics() {
 		populaterWg.Add(1)
 		mc := metricsCollector{
-			integration:         i,
-			db:                  dbWrapper,
-			wg:                  &populaterWg,
-			instanceLookUp:      instanceLookUp,
-			customMetricsQuery:  args.CustomMetricsQuery,
-			customMetricsConfig: args.CustomMetricsConfig,
-			skipMetricsGroups:   skipMetricsGroups,
+			integration:          i,
+			db:                   dbWrapper,
+			wg:                   &populaterWg,
+			instanceLookUp:       instanceLookUp,
+			customMetricsQuery:   args.CustomMetricsQuery,
+			customMetricsConfig:  args.CustomMetricsConfig,
+			skipMetricsGroups:    skipMetricsGroups,
+			includeMetricsGroups: includeMetricsGroups,
+			enableMetricsGroups:  enableMetricsGroups,
 		}
 		go mc.collect()
 	}
@@ -122,6 +188,10 @@ func main() {
 
 	populaterWg.Wait()
 
+	if err := stateStore.Save(); err != nil {
+		log.Error("Failed to save the state store: %s", err)
+	}
+
 	exitOnErr(i.Publish())
 }
 
@@ -170,6 +240,37 @@ func parseTablespaceWhitelist() error {
 	return json.Unmarshal([]byte(args.Tablespaces), &tablespaceWhiteList)
 }
 
+// parseSlowMetricsInterval parses the SLOW_METRICS_INTERVAL duration
+func parseSlowMetricsInterval() error {
+	slowMetricsInterval = 0
+
+	if args.SlowMetricsInterval == "" {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(args.SlowMetricsInterval)
+	if err != nil {
+		return fmt.Errorf("parsing SlowMetricsInterval: %w", err)
+	}
+
+	slowMetricsInterval = interval
+	return nil
+}
+
+// parseSchemaHealthExclude parses the SCHEMA_HEALTH_EXCLUDE JSON array
+func parseSchemaHealthExclude() error {
+	schemaHealthExclude = nil
+
+	if args.SchemaHealthExclude == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(args.SchemaHealthExclude), &schemaHealthExclude); err != nil {
+		return fmt.Errorf("decoding json SchemaHealthExclude: %w", err)
+	}
+	return nil
+}
+
 func parseSkipMetricsGroups() ([]string, error) {
 	var skipMetricsGroups []string
 
@@ -184,6 +285,59 @@ func parseSkipMetricsGroups() ([]string, error) {
 	return skipMetricsGroups, nil
 }
 
+func parseIncludeMetricsGroups() ([]string, error) {
+	var includeMetricsGroups []string
+
+	if args.IncludeMetricsGroups == "" {
+		return includeMetricsGroups, nil
+	}
+
+	if err := json.Unmarshal([]byte(args.IncludeMetricsGroups), &includeMetricsGroups); err != nil {
+		return nil, fmt.Errorf("decoding json IncludeMetricsGroups: %w", err)
+	}
+
+	return includeMetricsGroups, nil
+}
+
+func parseEnableMetricsGroups() ([]string, error) {
+	var enableMetricsGroups []string
+
+	if args.EnableMetricsGroups == "" {
+		return enableMetricsGroups, nil
+	}
+
+	if err := json.Unmarshal([]byte(args.EnableMetricsGroups), &enableMetricsGroups); err != nil {
+		return nil, fmt.Errorf("decoding json EnableMetricsGroups: %w", err)
+	}
+
+	return enableMetricsGroups, nil
+}
+
+// parseMetricFilters decodes the INCLUDE_METRICS and EXCLUDE_METRICS glob patterns
+func parseMetricFilters() error {
+	includeMetricPatterns, excludeMetricPatterns = nil, nil
+
+	if args.IncludeMetrics != "" {
+		if err := json.Unmarshal([]byte(args.IncludeMetrics), &includeMetricPatterns); err != nil {
+			return fmt.Errorf("decoding json IncludeMetrics: %w", err)
+		}
+	}
+
+	if args.ExcludeMetrics != "" {
+		if err := json.Unmarshal([]byte(args.ExcludeMetrics), &excludeMetricPatterns); err != nil {
+			return fmt.Errorf("decoding json ExcludeMetrics: %w", err)
+		}
+	}
+
+	for _, pattern := range append(includeMetricPatterns, excludeMetricPatterns...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid metric pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
 func createInstanceIDLookup(db database.DBWrapper) (map[string]string, error) {
 	const instanceQuery = `SELECT
 		INSTANCE_NAME, INST_ID
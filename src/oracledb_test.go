@@ -117,3 +117,26 @@ func Test_createInstanceIDLookup(t *testing.T) {
 		t.Errorf("Expected %+v got %+v", expected, out)
 	}
 }
+
+func Test_parseMetricFilters(t *testing.T) {
+	defer func() {
+		args = argumentList{}
+		includeMetricPatterns, excludeMetricPatterns = nil, nil
+	}()
+
+	args = argumentList{
+		IncludeMetrics: `["db.*PerTransaction"]`,
+		ExcludeMetrics: `["sga.*"]`,
+	}
+	if err := parseMetricFilters(); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if !reflect.DeepEqual(includeMetricPatterns, []string{"db.*PerTransaction"}) || !reflect.DeepEqual(excludeMetricPatterns, []string{"sga.*"}) {
+		t.Errorf("Unexpected patterns %v %v", includeMetricPatterns, excludeMetricPatterns)
+	}
+
+	args = argumentList{ExcludeMetrics: `["db.[*"]`}
+	if err := parseMetricFilters(); err == nil {
+		t.Error("Did not return expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+)
+
+const parallelQuerySample = "OracleParallelQuerySample"
+
+var oracleParallelServers = oracleMetricGroup{
+	name: "px_servers",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Statistic names are padded with spaces
+		query := `
+		SELECT INST_ID, TRIM(STATISTIC) AS NAME, VALUE
+		FROM gv$px_process_sysstat
+		WHERE`
+		query += inMetrics("TRIM(STATISTIC)", metrics)
+		return query
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "px.serversBusy",
+			identifier:    "Servers In Use",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.serversIdle",
+			identifier:    "Servers Available",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.serversHighwater",
+			identifier:    "Servers Highwater",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.serversStartedPerSecond",
+			identifier:    "Servers Started",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.serversShutdownPerSecond",
+			identifier:    "Servers Shutdown",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.serversCleanedUpPerSecond",
+			identifier:    "Servers Cleaned Up",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+	},
+
+	metricsGenerator: rowMetricsGenerator,
+}
+
+// oracleParallelQueries sends a sample per parallel query, so it is only collected when enabled
+var oracleParallelQueries = oracleMetricGroup{
+	name:  "px_sessions",
+	optIn: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Only the PX servers of gv$px_session have a query coordinator instance. Instances
+		// without parallel queries have a single row of NULLs, which reports nothing
+		return `
+		SELECT
+			i.INST_ID,
+			p.QCSID,
+			p.QCSERIAL#,
+			p.SERVERS,
+			p.DEGREE,
+			p.REQUESTED_DEGREE,
+			q.USERNAME,
+			q.SQL_ID
+		FROM gv$instance i
+		LEFT JOIN (
+			SELECT
+				QCINST_ID,
+				QCSID,
+				QCSERIAL#,
+				COUNT(*) AS SERVERS,
+				MAX(DEGREE) AS DEGREE,
+				MAX(REQ_DEGREE) AS REQUESTED_DEGREE
+			FROM gv$px_session
+			WHERE QCINST_ID IS NOT NULL
+			GROUP BY QCINST_ID, QCSID, QCSERIAL#
+		) p ON p.QCINST_ID = i.INST_ID
+		LEFT JOIN gv$session q ON q.INST_ID = p.QCINST_ID AND q.SID = p.QCSID AND q.SERIAL# = p.QCSERIAL#`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "px.query.servers",
+			identifier:    "SERVERS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.query.degree",
+			identifier:    "DEGREE",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.query.requestedDegree",
+			identifier:    "REQUESTED_DEGREE",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.query.username",
+			identifier:    "USERNAME",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.query.sqlId",
+			identifier:    "SQL_ID",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			"instanceID":                         getInstanceIDString(row["INST_ID"]),
+			sampleMetadataKey:                    parallelQuerySample,
+			dimensionMetadataPrefix + "qcSid":    stringValue(row["QCSID"]),
+			dimensionMetadataPrefix + "qcSerial": stringValue(row["QCSERIAL#"]),
+		}
+	}),
+}
+
+var oracleParallelDowngrades = oracleMetricGroup{
+	name: "px_downgrades",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		query := `
+		SELECT INST_ID, NAME, VALUE
+		FROM gv$sysstat
+		WHERE`
+		query += inMetrics("NAME", metrics)
+		return query
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "px.operationsNotDowngradedPerSecond",
+			identifier:    "Parallel operations not downgraded",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.operationsDowngradedToSerialPerSecond",
+			identifier:    "Parallel operations downgraded to serial",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.operationsDowngraded75To99PctPerSecond",
+			identifier:    "Parallel operations downgraded 75 to 99 pct",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.operationsDowngraded50To75PctPerSecond",
+			identifier:    "Parallel operations downgraded 50 to 75 pct",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.operationsDowngraded25To50PctPerSecond",
+			identifier:    "Parallel operations downgraded 25 to 50 pct",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+		{
+			name:          "px.operationsDowngraded1To25PctPerSecond",
+			identifier:    "Parallel operations downgraded 1 to 25 pct",
+			metricType:    metric.RATE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: rowMetricsGenerator,
+}
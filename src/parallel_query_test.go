@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+)
+
+func TestParallelServers(t *testing.T) {
+	if query := oracleParallelServers.sqlQuery(oracleParallelServers.metrics); !strings.Contains(query, "TRIM(STATISTIC) IN ('Servers In Use',") {
+		t.Errorf("Expected the query to match trimmed statistic names, got %s", query)
+	}
+
+	senders := collectMetricGroup(t, oracleParallelServers, sqlmock.NewRows([]string{"INST_ID", "NAME", "VALUE"}).
+		AddRow(1, "Servers In Use", 16).
+		AddRow(1, "Servers Available", 4).
+		AddRow(1, "Servers Started", 120))
+
+	values := make(map[string]*newrelicMetric)
+	for _, sender := range senders {
+		values[sender.metric.name] = sender.metric
+	}
+	if len(values) != 3 || values["px.serversBusy"].value != 16.0 || values["px.serversIdle"].value != 4.0 {
+		t.Errorf("Unexpected server metrics %v", values)
+	}
+	if values["px.serversStartedPerSecond"].metricType != metric.RATE {
+		t.Errorf("Expected servers started to be reported as a rate")
+	}
+}
+
+func TestParallelQueries(t *testing.T) {
+	columns := []string{"INST_ID", "QCSID", "QCSERIAL#", "SERVERS", "DEGREE", "REQUESTED_DEGREE", "USERNAME", "SQL_ID"}
+	senders := collectMetricGroup(t, oracleParallelQueries, sqlmock.NewRows(columns).
+		AddRow("2", 812, 40211, 8, 4, 16, "REPORTS", "6g5kzxb3q9dmp"))
+
+	values := make(map[string]interface{})
+	for _, sender := range senders {
+		if sender.metadata["instanceID"] != "2" || sender.metadata[sampleMetadataKey] != parallelQuerySample || sender.metadata[dimensionMetadataPrefix+"qcSid"] != "812" || sender.metadata[dimensionMetadataPrefix+"qcSerial"] != "40211" {
+			t.Errorf("Unexpected metadata %v", sender.metadata)
+		}
+		values[sender.metric.name] = sender.metric.value
+	}
+	if values["px.query.degree"] != int64(4) || values["px.query.requestedDegree"] != int64(16) || values["px.query.username"] != "REPORTS" {
+		t.Errorf("Unexpected parallel query metrics %v", values)
+	}
+}
+
+func TestParallelQueries_NoQueries(t *testing.T) {
+	columns := []string{"INST_ID", "QCSID", "QCSERIAL#", "SERVERS", "DEGREE", "REQUESTED_DEGREE", "USERNAME", "SQL_ID"}
+	senders := collectMetricGroup(t, oracleParallelQueries, sqlmock.NewRows(columns).
+		AddRow("1", nil, nil, nil, nil, nil, nil, nil))
+	if len(senders) != 0 {
+		t.Errorf("Expected no metrics for an instance without parallel queries, got %d", len(senders))
+	}
+}
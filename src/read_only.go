@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	errUnterminatedSQL = errors.New("unterminated comment, string or quoted identifier")
+	errEmptyStatement  = errors.New("empty statement")
+)
+
+// customQueryAllowlist holds the hashes of the custom queries allowed to run,
+// decoded from CUSTOM_QUERY_ALLOWLIST. When empty any read-only query is allowed
+var customQueryAllowlist []string
+
+// parseCustomQueryAllowlist decodes the CUSTOM_QUERY_ALLOWLIST hashes
+func parseCustomQueryAllowlist() error {
+	customQueryAllowlist = nil
+
+	if args.CustomQueryAllowlist == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(args.CustomQueryAllowlist), &customQueryAllowlist); err != nil {
+		return fmt.Errorf("decoding json CustomQueryAllowlist: %w", err)
+	}
+
+	return nil
+}
+
+// customQueryHash returns the hash identifying a custom query in CUSTOM_QUERY_ALLOWLIST.
+// Whitespace is normalized so reformatting the YAML file does not change it
+func customQueryHash(query string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(formatQueryForLogging(query))))
+}
+
+// checkCustomQuery returns an error when a custom query is not a single read-only
+// statement or is not in CUSTOM_QUERY_ALLOWLIST
+func checkCustomQuery(query string) error {
+	if err := checkReadOnlyStatement(query); err != nil {
+		return err
+	}
+
+	if len(customQueryAllowlist) == 0 {
+		return nil
+	}
+
+	hash := customQueryHash(query)
+	for _, allowed := range customQueryAllowlist {
+		if strings.EqualFold(allowed, hash) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("query hash %s is not in CUSTOM_QUERY_ALLOWLIST", hash)
+}
+
+// checkReadOnlyStatement returns an error unless query is a single SELECT or WITH
+// statement. Comments and literals are ignored, and a trailing semicolon is accepted
+func checkReadOnlyStatement(query string) error {
+	stripped, err := stripSQLCommentsAndLiterals(query)
+	if err != nil {
+		return err
+	}
+
+	stripped = strings.TrimSuffix(strings.TrimSpace(stripped), ";")
+	if strings.Contains(stripped, ";") {
+		return errors.New("only a single statement is allowed")
+	}
+
+	words := strings.FieldsFunc(strings.ToUpper(stripped), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$' && r != '#'
+	})
+	if len(words) == 0 {
+		return errEmptyStatement
+	}
+
+	if words[0] != "SELECT" && words[0] != "WITH" {
+		return fmt.Errorf("only SELECT and WITH statements are allowed, got %s", words[0])
+	}
+
+	if words[0] == "WITH" && len(words) > 1 && (words[1] == "FUNCTION" || words[1] == "PROCEDURE") {
+		return errors.New("PL/SQL declarations are not allowed in WITH clauses")
+	}
+
+	for i := 0; i+1 < len(words); i++ {
+		if words[i] == "FOR" && words[i+1] == "UPDATE" {
+			return errors.New("SELECT FOR UPDATE is not allowed")
+		}
+	}
+
+	return nil
+}
+
+// stripSQLCommentsAndLiterals replaces comments, string literals and quoted
+// identifiers with spaces, so keywords and semicolons inside them are not matched
+func stripSQLCommentsAndLiterals(query string) (string, error) {
+	var b strings.Builder
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case runes[i] == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			if i+1 >= len(runes) {
+				return "", errUnterminatedSQL
+			}
+			i++
+		case (runes[i] == 'q' || runes[i] == 'Q') && i+2 < len(runes) && runes[i+1] == '\'' && !isIdentifierRune(runes, i-1):
+			// Alternative quoting, e.g. q'[it's]'
+			closing := alternativeQuoteClosing(runes[i+2])
+			i += 3
+			for i+1 < len(runes) && !(runes[i] == closing && runes[i+1] == '\'') {
+				i++
+			}
+			if i+1 >= len(runes) {
+				return "", errUnterminatedSQL
+			}
+			i++
+		case runes[i] == '\'' || runes[i] == '"':
+			quote := runes[i]
+			i++
+			for ; i < len(runes); i++ {
+				if runes[i] != quote {
+					continue
+				}
+				if quote == '\'' && i+1 < len(runes) && runes[i+1] == '\'' {
+					i++
+					continue
+				}
+				break
+			}
+			if i >= len(runes) {
+				return "", errUnterminatedSQL
+			}
+		default:
+			b.WriteRune(runes[i])
+			continue
+		}
+		b.WriteRune(' ')
+	}
+
+	return b.String(), nil
+}
+
+func isIdentifierRune(runes []rune, i int) bool {
+	if i < 0 {
+		return false
+	}
+	r := runes[i]
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$' || r == '#'
+}
+
+func alternativeQuoteClosing(opening rune) rune {
+	switch opening {
+	case '[':
+		return ']'
+	case '{':
+		return '}'
+	case '(':
+		return ')'
+	case '<':
+		return '>'
+	default:
+		return opening
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_checkReadOnlyStatement(t *testing.T) {
+	testCases := []struct {
+		name    string
+		query   string
+		allowed bool
+	}{
+		{"select", "SELECT 1 FROM dual", true},
+		{"lowercase with trailing semicolon", "select 1 from dual;", true},
+		{"with clause", "WITH t AS (SELECT 1 a FROM dual) SELECT a FROM t", true},
+		{"parenthesized", "(SELECT 1 FROM dual) UNION (SELECT 2 FROM dual)", true},
+		{"leading comments", "-- note\n/* header */ SELECT 1 FROM dual", true},
+		{"keywords in literals", "SELECT 'x; DELETE FROM t' AS a, \"FOR UPDATE\" FROM dual", true},
+		{"alternative quoting", "SELECT q'[it's; fine]' FROM dual", true},
+		{"delete", "DELETE FROM audit_log", false},
+		{"plsql block", "BEGIN NULL; END;", false},
+		{"two statements", "SELECT 1 FROM dual; DROP TABLE t", false},
+		{"statement after comment", "SELECT 1 FROM dual; -- comment\nDELETE FROM t", false},
+		{"for update", "SELECT * FROM t FOR UPDATE NOWAIT", false},
+		{"with function", "WITH FUNCTION f RETURN NUMBER IS BEGIN RETURN 1; END; SELECT f FROM dual", false},
+		{"unterminated literal", "SELECT 'abc FROM dual", false},
+		{"unterminated comment", "SELECT 1 FROM dual /* comment", false},
+		{"empty", " ; ", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkReadOnlyStatement(tc.query)
+			if tc.allowed && err != nil {
+				t.Errorf("Expected query to be allowed, got %s", err)
+			}
+			if !tc.allowed && err == nil {
+				t.Error("Expected query to be rejected")
+			}
+		})
+	}
+}
+
+func Test_checkCustomQuery_Allowlist(t *testing.T) {
+	defer func() {
+		args = argumentList{}
+		customQueryAllowlist = nil
+	}()
+
+	const query = "SELECT 1\n  FROM dual"
+
+	args = argumentList{CustomQueryAllowlist: `["` + customQueryHash("SELECT 1 FROM dual") + `"]`}
+	if err := parseCustomQueryAllowlist(); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if err := checkCustomQuery(query); err != nil {
+		t.Errorf("Expected allowlisted query to be allowed, got %s", err)
+	}
+
+	if err := checkCustomQuery("SELECT 2 FROM dual"); err == nil {
+		t.Error("Expected query missing from the allowlist to be rejected")
+	}
+}
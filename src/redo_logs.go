@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const redoLogSample = "OracleRedoLogSample"
+
+// redoLogStatuses are the gv$log statuses counted on the instance
+var redoLogStatuses = map[string]string{
+	"CURRENT":  "CURRENT_GROUPS",
+	"ACTIVE":   "ACTIVE_GROUPS",
+	"INACTIVE": "INACTIVE_GROUPS",
+	"UNUSED":   "UNUSED_GROUPS",
+}
+
+var oracleRedoLogs = oracleMetricGroup{
+	name: "redo_logs",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// gv$log returns the groups of every thread, so only the thread of the instance is kept
+		return `
+		SELECT
+			l.INST_ID,
+			l.GROUP#,
+			l.THREAD#,
+			l.MEMBERS,
+			l.BYTES,
+			l.STATUS,
+			l.ARCHIVED,
+			(
+				SELECT COUNT(*)
+				FROM gv$logfile f
+				WHERE f.INST_ID = l.INST_ID AND f.GROUP# = l.GROUP# AND f.STATUS IN ('INVALID', 'STALE')
+			) AS INVALID_MEMBERS
+		FROM gv$log l
+		JOIN gv$instance i ON i.INST_ID = l.INST_ID AND i.THREAD# = l.THREAD#
+		ORDER BY l.INST_ID, l.GROUP#`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "redo.group.members",
+			identifier:    "MEMBERS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.group.sizeInBytes",
+			identifier:    "BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.group.status",
+			identifier:    "STATUS",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.group.archived",
+			identifier:    "ARCHIVED",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.group.invalidMembers",
+			identifier:    "INVALID_MEMBERS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.groups",
+			identifier:    "GROUPS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.currentGroups",
+			identifier:    "CURRENT_GROUPS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.activeGroups",
+			identifier:    "ACTIVE_GROUPS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.inactiveGroups",
+			identifier:    "INACTIVE_GROUPS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.unusedGroups",
+			identifier:    "UNUSED_GROUPS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.unarchivedGroups",
+			identifier:    "UNARCHIVED_GROUPS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.invalidMembers",
+			identifier:    "TOTAL_INVALID_MEMBERS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: redoLogsGenerator,
+}
+
+// redoLogsGenerator reports every redo log group of an instance on a redo log sample,
+// and the number of groups per status on the instance
+func redoLogsGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	var instanceIDs []string
+	totals := make(map[string]map[string]float64)
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		instanceID := getInstanceIDString(rowMap["INST_ID"])
+		if _, ok := totals[instanceID]; !ok {
+			instanceIDs = append(instanceIDs, instanceID)
+			totals[instanceID] = map[string]float64{"GROUPS": 0, "CURRENT_GROUPS": 0, "ACTIVE_GROUPS": 0, "INACTIVE_GROUPS": 0, "UNUSED_GROUPS": 0, "UNARCHIVED_GROUPS": 0, "TOTAL_INVALID_MEMBERS": 0}
+		}
+
+		instanceTotals := totals[instanceID]
+		instanceTotals["GROUPS"]++
+		if identifier, ok := redoLogStatuses[stringValue(rowMap["STATUS"])]; ok {
+			instanceTotals[identifier]++
+		}
+		if stringValue(rowMap["ARCHIVED"]) == "NO" {
+			instanceTotals["UNARCHIVED_GROUPS"]++
+		}
+		invalidMembers, _ := toFloat64(sanitizedValue(rowMap["INVALID_MEMBERS"]))
+		instanceTotals["TOTAL_INVALID_MEMBERS"] += invalidMembers
+
+		metadata := map[string]string{
+			"instanceID":                       instanceID,
+			sampleMetadataKey:                  redoLogSample,
+			dimensionMetadataPrefix + "group":  stringValue(rowMap["GROUP#"]),
+			dimensionMetadataPrefix + "thread": stringValue(rowMap["THREAD#"]),
+		}
+		for _, metric := range metrics {
+			if value, ok := rowMap[metric.identifier]; ok && value != nil && metricEnabled(metric) {
+				metricChan <- newrelicMetricSender{
+					metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: value},
+					metadata: metadata,
+				}
+			}
+		}
+	}
+
+	for _, instanceID := range instanceIDs {
+		for _, metric := range metrics {
+			if value, ok := totals[instanceID][metric.identifier]; ok && metricEnabled(metric) {
+				metricChan <- newrelicMetricSender{
+					metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: value},
+					metadata: map[string]string{"instanceID": instanceID},
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// oracleRedoLogSwitches reads the log history of the last hour, so it runs as a slow
+// group instead of scanning it on every run
+var oracleRedoLogSwitches = oracleMetricGroup{
+	name: "redo_log_switches",
+	slow: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			i.INST_ID,
+			COUNT(h.FIRST_TIME) AS SWITCHES,
+			(MAX(h.FIRST_TIME) - MIN(h.FIRST_TIME)) * 86400 / NULLIF(COUNT(h.FIRST_TIME) - 1, 0) AS AVERAGE_INTERVAL
+		FROM gv$instance i
+		LEFT JOIN v$log_history h ON h.THREAD# = i.THREAD# AND h.FIRST_TIME > SYSDATE - 1/24
+		GROUP BY i.INST_ID`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "redo.logSwitchesLastHour",
+			identifier:    "SWITCHES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "redo.averageLogSwitchIntervalInSeconds",
+			identifier:    "AVERAGE_INTERVAL",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{"instanceID": getInstanceIDString(row["INST_ID"])}
+	}),
+}
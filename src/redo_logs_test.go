@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRedoLogs(t *testing.T) {
+	columns := []string{"INST_ID", "GROUP#", "THREAD#", "MEMBERS", "BYTES", "STATUS", "ARCHIVED", "INVALID_MEMBERS"}
+	senders := collectMetricGroup(t, oracleRedoLogs, sqlmock.NewRows(columns).
+		AddRow("1", 1, 1, 2, 209715200, "CURRENT", "NO", 0).
+		AddRow("1", 2, 1, 2, 209715200, "ACTIVE", "YES", 1).
+		AddRow("1", 3, 1, 2, 209715200, "INACTIVE", "YES", 0).
+		AddRow("2", 4, 2, 1, 104857600, "UNUSED", "YES", 0))
+
+	groups := make(map[string]map[string]interface{})
+	instances := make(map[string]map[string]interface{})
+	for _, sender := range senders {
+		if sender.metadata[sampleMetadataKey] == redoLogSample {
+			if sender.metadata[dimensionMetadataPrefix+"thread"] == "" {
+				t.Errorf("Expected a thread dimension, got %v", sender.metadata)
+			}
+			group := sender.metadata[dimensionMetadataPrefix+"group"]
+			if groups[group] == nil {
+				groups[group] = make(map[string]interface{})
+			}
+			groups[group][sender.metric.name] = sender.metric.value
+			continue
+		}
+		instanceID := sender.metadata["instanceID"]
+		if instances[instanceID] == nil {
+			instances[instanceID] = make(map[string]interface{})
+		}
+		instances[instanceID][sender.metric.name] = sender.metric.value
+	}
+
+	if len(groups) != 4 || groups["2"]["redo.group.status"] != "ACTIVE" || groups["2"]["redo.group.invalidMembers"] != int64(1) || groups["1"]["redo.group.archived"] != "NO" {
+		t.Errorf("Unexpected redo log groups %v", groups)
+	}
+
+	expected := map[string]float64{
+		"redo.groups":           3,
+		"redo.currentGroups":    1,
+		"redo.activeGroups":     1,
+		"redo.inactiveGroups":   1,
+		"redo.unusedGroups":     0,
+		"redo.unarchivedGroups": 1,
+		"redo.invalidMembers":   1,
+	}
+	for name, value := range expected {
+		if instances["1"][name] != value {
+			t.Errorf("Expected %s to be %v on instance 1, got %v", name, value, instances["1"][name])
+		}
+	}
+	if instances["2"]["redo.unusedGroups"] != float64(1) || instances["2"]["redo.groups"] != float64(1) {
+		t.Errorf("Unexpected metrics on instance 2 %v", instances["2"])
+	}
+}
+
+func TestRedoLogSwitches(t *testing.T) {
+	senders := collectMetricGroup(t, oracleRedoLogSwitches, sqlmock.NewRows([]string{"INST_ID", "SWITCHES", "AVERAGE_INTERVAL"}).
+		AddRow("1", 7, 540.5).
+		AddRow("2", 1, nil))
+
+	values := make(map[string]interface{})
+	for _, sender := range senders {
+		values[sender.metadata["instanceID"]+" "+sender.metric.name] = sender.metric.value
+	}
+	if values["1 redo.averageLogSwitchIntervalInSeconds"] != 540.5 || values["2 redo.logSwitchesLastHour"] != int64(1) {
+		t.Errorf("Unexpected log switch metrics %v", values)
+	}
+	if _, ok := values["2 redo.averageLogSwitchIntervalInSeconds"]; ok {
+		t.Errorf("Expected no average interval with a single log switch")
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const (
+	schedulerJobSample = "OracleSchedulerJobSample"
+	schedulerJobFailed = "Scheduler job failed"
+	legacyJobFailed    = "Job failed"
+)
+
+var (
+	schedulerLastLogIDKey = stateKey("scheduler", "last-log-id")
+	legacyJobFailuresKey  = stateKey("legacy-jobs", "failures")
+)
+
+var oracleSchedulerJobs = oracleMetricGroup{
+	name: "scheduler_jobs",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// The jobs of the database are only counted on the instance the integration is
+		// connected to, so they are not added up once per instance
+		return `
+		SELECT
+			i.INST_ID,
+			CASE WHEN i.INST_ID = SYS_CONTEXT('USERENV', 'INSTANCE') THEN j.BROKEN_JOBS END AS BROKEN_JOBS,
+			CASE WHEN i.INST_ID = SYS_CONTEXT('USERENV', 'INSTANCE') THEN j.FAILED_JOBS END AS FAILED_JOBS,
+			CASE WHEN i.INST_ID = SYS_CONTEXT('USERENV', 'INSTANCE') THEN j.DISABLED_JOBS END AS DISABLED_JOBS,
+			NVL(r.RUNNING_JOBS, 0) AS RUNNING_JOBS,
+			r.MAX_ELAPSED_SECONDS
+		FROM gv$instance i
+		CROSS JOIN (
+			SELECT
+				NVL(SUM(CASE WHEN STATE = 'BROKEN' THEN 1 ELSE 0 END), 0) AS BROKEN_JOBS,
+				NVL(SUM(CASE WHEN STATE = 'FAILED' THEN 1 ELSE 0 END), 0) AS FAILED_JOBS,
+				NVL(SUM(CASE WHEN STATE = 'DISABLED' THEN 1 ELSE 0 END), 0) AS DISABLED_JOBS
+			FROM dba_scheduler_jobs
+		) j
+		LEFT JOIN (
+			SELECT
+				RUNNING_INSTANCE,
+				COUNT(*) AS RUNNING_JOBS,
+				MAX(EXTRACT(DAY FROM ELAPSED_TIME) * 86400 + EXTRACT(HOUR FROM ELAPSED_TIME) * 3600
+					+ EXTRACT(MINUTE FROM ELAPSED_TIME) * 60 + EXTRACT(SECOND FROM ELAPSED_TIME)) AS MAX_ELAPSED_SECONDS
+			FROM dba_scheduler_running_jobs
+			GROUP BY RUNNING_INSTANCE
+		) r ON r.RUNNING_INSTANCE = i.INST_ID`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "scheduler.brokenJobs",
+			identifier:    "BROKEN_JOBS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "scheduler.failedJobs",
+			identifier:    "FAILED_JOBS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "scheduler.disabledJobs",
+			identifier:    "DISABLED_JOBS",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "scheduler.runningJobs",
+			identifier:    "RUNNING_JOBS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "scheduler.longestRunningJobElapsedSeconds",
+			identifier:    "MAX_ELAPSED_SECONDS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{"instanceID": getInstanceIDString(row["INST_ID"])}
+	}),
+}
+
+var oracleSchedulerRunningJobs = oracleMetricGroup{
+	name: "scheduler_running_jobs",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Instances without running jobs have a single row of NULLs, which reports nothing
+		return `
+		SELECT
+			i.INST_ID,
+			r.OWNER,
+			r.JOB_NAME,
+			EXTRACT(DAY FROM r.ELAPSED_TIME) * 86400 + EXTRACT(HOUR FROM r.ELAPSED_TIME) * 3600
+				+ EXTRACT(MINUTE FROM r.ELAPSED_TIME) * 60 + EXTRACT(SECOND FROM r.ELAPSED_TIME) AS ELAPSED_SECONDS
+		FROM gv$instance i
+		LEFT JOIN dba_scheduler_running_jobs r ON r.RUNNING_INSTANCE = i.INST_ID`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "scheduler.job.elapsedSeconds",
+			identifier:    "ELAPSED_SECONDS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			"instanceID":                        getInstanceIDString(row["INST_ID"]),
+			sampleMetadataKey:                   schedulerJobSample,
+			dimensionMetadataPrefix + "owner":   stringValue(row["OWNER"]),
+			dimensionMetadataPrefix + "jobName": stringValue(row["JOB_NAME"]),
+		}
+	}),
+}
+
+var oracleSchedulerJobFailures = oracleMetricGroup{
+	name: "scheduler_job_failures",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// The latest log ID is always returned, with a row of NULLs when there are no
+		// new failures, and the first run only looks it up so past failures are not reported
+		condition := "1 = 0"
+		if lastLogID, ok := schedulerLastLogID(); ok {
+			condition = fmt.Sprintf("d.LOG_ID > %d AND d.LOG_ID <= m.MAX_LOG_ID AND d.STATUS <> 'SUCCEEDED'", lastLogID)
+		}
+
+		return `
+		SELECT
+			m.MAX_LOG_ID,
+			d.LOG_ID,
+			NVL(d.INSTANCE_ID, SYS_CONTEXT('USERENV', 'INSTANCE')) AS INST_ID,
+			d.OWNER,
+			d.JOB_NAME,
+			d.STATUS,
+			d.ERROR#,
+			d.ADDITIONAL_INFO,
+			TO_CHAR(d.ACTUAL_START_DATE, 'YYYY-MM-DD HH24:MI:SS TZH:TZM') AS ACTUAL_START_DATE
+		FROM (SELECT MAX(LOG_ID) AS MAX_LOG_ID FROM dba_scheduler_job_run_details) m
+		LEFT JOIN dba_scheduler_job_run_details d ON ` + condition + `
+		ORDER BY d.LOG_ID`
+	},
+
+	events: []string{schedulerJobFailed},
+
+	metricsGenerator: func(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+		columnNames, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve columns from rows")
+		}
+
+		// The cursor moves to the latest log ID whatever the status of the runs before it
+		lastLogID, seen := schedulerLastLogID()
+		for rows.Next() {
+			rowMap, err := scanRowMap(rows, columnNames)
+			if err != nil {
+				return err
+			}
+
+			if maxLogID, ok := toFloat64(sanitizedValue(rowMap["MAX_LOG_ID"])); ok && int64(maxLogID) > lastLogID {
+				lastLogID = int64(maxLogID)
+			}
+
+			if !seen || rowMap["LOG_ID"] == nil {
+				continue
+			}
+
+			logID, err := strconv.ParseInt(stringValue(rowMap["LOG_ID"]), 10, 64)
+			if err != nil {
+				return fmt.Errorf("parsing scheduler log ID: %w", err)
+			}
+
+			metricChan <- newrelicMetricSender{
+				metadata: map[string]string{"instanceID": getInstanceIDString(rowMap["INST_ID"])},
+				event: event.NewWithAttributes(schedulerJobFailed, event.NotificationEventCategory, map[string]interface{}{
+					"owner":          stringValue(rowMap["OWNER"]),
+					"jobName":        stringValue(rowMap["JOB_NAME"]),
+					"status":         stringValue(rowMap["STATUS"]),
+					"errorNumber":    stringValue(rowMap["ERROR#"]),
+					"additionalInfo": truncateAttribute(stringValue(rowMap["ADDITIONAL_INFO"])),
+					"startDate":      stringValue(rowMap["ACTUAL_START_DATE"]),
+					"logId":          logID,
+				}),
+			}
+		}
+
+		stateStore.Set(schedulerLastLogIDKey, lastLogID)
+		return nil
+	},
+}
+
+// schedulerLastLogID returns the latest scheduler log ID seen by a previous run
+func schedulerLastLogID() (int64, bool) {
+	var lastLogID int64
+	if _, err := stateStore.Get(schedulerLastLogIDKey, &lastLogID); err != nil {
+		return 0, false
+	}
+	return lastLogID, true
+}
+
+// oracleLegacyJobs covers the jobs submitted with DBMS_JOB, the only jobs available
+// in 11g releases that don't use the scheduler
+var oracleLegacyJobs = oracleMetricGroup{
+	name:       "legacy_jobs",
+	maxVersion: "11",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Instances without jobs have a single row of NULLs, so their counts are reported
+		return `
+		SELECT
+			i.INST_ID,
+			j.JOB,
+			j.SCHEMA_USER,
+			j.WHAT,
+			j.BROKEN,
+			j.FAILURES,
+			j.RUNNING
+		FROM gv$instance i
+		LEFT JOIN (
+			SELECT
+				NVL(NULLIF(j.INSTANCE, 0), SYS_CONTEXT('USERENV', 'INSTANCE')) AS INST_ID,
+				j.JOB,
+				j.SCHEMA_USER,
+				j.WHAT,
+				j.BROKEN,
+				j.FAILURES,
+				CASE WHEN r.JOB IS NULL THEN 0 ELSE 1 END AS RUNNING
+			FROM dba_jobs j
+			LEFT JOIN dba_jobs_running r ON r.JOB = j.JOB
+		) j ON j.INST_ID = i.INST_ID`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "scheduler.legacyBrokenJobs",
+			identifier:    "BROKEN",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "scheduler.legacyFailingJobs",
+			identifier:    "FAILURES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "scheduler.legacyRunningJobs",
+			identifier:    "RUNNING",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	events: []string{legacyJobFailed},
+
+	metricsGenerator: legacyJobsGenerator,
+}
+
+// legacyJobsGenerator counts the broken, failing and running DBMS_JOB jobs of every
+// instance, and sends an event when the failure count of a job increases
+func legacyJobsGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	var previous map[string]float64
+	if _, err := stateStore.Get(legacyJobFailuresKey, &previous); err != nil {
+		previous = nil
+	}
+
+	var instanceIDs []string
+	counts := make(map[string]map[string]int)
+	failures := make(map[string]float64)
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		instanceID := getInstanceIDString(rowMap["INST_ID"])
+		if _, ok := counts[instanceID]; !ok {
+			instanceIDs = append(instanceIDs, instanceID)
+			counts[instanceID] = make(map[string]int)
+		}
+		if rowMap["JOB"] == nil {
+			continue
+		}
+
+		jobFailures, _ := toFloat64(sanitizedValue(rowMap["FAILURES"]))
+		running, _ := toFloat64(sanitizedValue(rowMap["RUNNING"]))
+		if stringValue(rowMap["BROKEN"]) == "Y" {
+			counts[instanceID]["BROKEN"]++
+		}
+		if jobFailures > 0 {
+			counts[instanceID]["FAILURES"]++
+		}
+		if running > 0 {
+			counts[instanceID]["RUNNING"]++
+		}
+
+		job := stringValue(rowMap["JOB"])
+		failures[job] = jobFailures
+		if before, ok := previous[job]; ok && jobFailures > before {
+			metricChan <- newrelicMetricSender{
+				metadata: map[string]string{"instanceID": instanceID},
+				event: event.NewWithAttributes(legacyJobFailed, event.NotificationEventCategory, map[string]interface{}{
+					"owner":    stringValue(rowMap["SCHEMA_USER"]),
+					"jobName":  job,
+					"what":     truncateAttribute(stringValue(rowMap["WHAT"])),
+					"failures": jobFailures,
+					"broken":   stringValue(rowMap["BROKEN"]),
+				}),
+			}
+		}
+	}
+
+	stateStore.Set(legacyJobFailuresKey, failures)
+
+	for _, metric := range metrics {
+		if !metricEnabled(metric) {
+			continue
+		}
+		for _, instanceID := range instanceIDs {
+			metricChan <- newrelicMetricSender{
+				metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: counts[instanceID][metric.identifier]},
+				metadata: map[string]string{"instanceID": instanceID},
+			}
+		}
+	}
+
+	return nil
+}
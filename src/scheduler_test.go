@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+func TestSchedulerJobFailures(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	if query := oracleSchedulerJobFailures.sqlQuery(nil); !strings.Contains(query, "SELECT MAX(LOG_ID)") || !strings.Contains(query, "1 = 0") {
+		t.Errorf("Expected the first run to only look up the latest log ID, got %s", query)
+	}
+
+	columns := []string{"MAX_LOG_ID", "LOG_ID", "INST_ID", "OWNER", "JOB_NAME", "STATUS", "ERROR#", "ADDITIONAL_INFO", "ACTUAL_START_DATE"}
+	senders := collectMetricGroup(t, oracleSchedulerJobFailures, sqlmock.NewRows(columns).
+		AddRow(41, nil, nil, nil, nil, nil, nil, nil, nil))
+	if len(senders) != 0 {
+		t.Fatalf("Expected no events for failures before the first run, got %d", len(senders))
+	}
+
+	if query := oracleSchedulerJobFailures.sqlQuery(nil); !strings.Contains(query, "LOG_ID > 41") {
+		t.Errorf("Expected the query to start after the latest log ID, got %s", query)
+	}
+
+	senders = collectMetricGroup(t, oracleSchedulerJobFailures, sqlmock.NewRows(columns).
+		AddRow(48, 45, 2, "BATCH", "NIGHTLY_LOAD", "FAILED", 20001, "ORA-20001: no data", "2024-01-02 02:00:00 +00:00"))
+	if len(senders) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(senders))
+	}
+	attributes := senders[0].event.Attributes
+	if senders[0].metadata["instanceID"] != "2" || attributes["jobName"] != "NIGHTLY_LOAD" || attributes["errorNumber"] != "20001" || attributes["additionalInfo"] != "ORA-20001: no data" {
+		t.Errorf("Unexpected event %+v with metadata %v", senders[0].event, senders[0].metadata)
+	}
+
+	// The runs after the failure succeeded, and the cursor still moves past them
+	if lastLogID, _ := schedulerLastLogID(); lastLogID != 48 {
+		t.Errorf("Expected last log ID 48, got %d", lastLogID)
+	}
+}
+
+func TestSchedulerJobFailures_OnlySuccesses(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+	stateStore.Set(schedulerLastLogIDKey, int64(10))
+
+	columns := []string{"MAX_LOG_ID", "LOG_ID", "INST_ID", "OWNER", "JOB_NAME", "STATUS", "ERROR#", "ADDITIONAL_INFO", "ACTUAL_START_DATE"}
+	senders := collectMetricGroup(t, oracleSchedulerJobFailures, sqlmock.NewRows(columns).
+		AddRow(25, nil, nil, nil, nil, nil, nil, nil, nil))
+	if len(senders) != 0 {
+		t.Errorf("Expected no events, got %d", len(senders))
+	}
+	if lastLogID, _ := schedulerLastLogID(); lastLogID != 25 {
+		t.Errorf("Expected last log ID 25, got %d", lastLogID)
+	}
+}
+
+func TestSchedulerRunningJobs_NoJobs(t *testing.T) {
+	columns := []string{"INST_ID", "OWNER", "JOB_NAME", "ELAPSED_SECONDS"}
+	senders := collectMetricGroup(t, oracleSchedulerRunningJobs, sqlmock.NewRows(columns).
+		AddRow("1", nil, nil, nil).
+		AddRow("2", nil, nil, nil))
+	if len(senders) != 0 {
+		t.Errorf("Expected no metrics for instances without running jobs, got %d", len(senders))
+	}
+}
+
+func TestLegacyJobs(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "JOB", "SCHEMA_USER", "WHAT", "BROKEN", "FAILURES", "RUNNING"}
+
+	senders := collectMetricGroup(t, oracleLegacyJobs, sqlmock.NewRows(columns).
+		AddRow("1", 21, "HR", "purge_audit;", "N", 0, 1).
+		AddRow("1", 22, "HR", "refresh_totals;", "N", 1, 0))
+	counts := make(map[string]interface{})
+	for _, sender := range senders {
+		if sender.event != nil {
+			t.Errorf("Expected no events on the first run, got %+v", sender.event)
+			continue
+		}
+		counts[sender.metric.name] = sender.metric.value
+	}
+	if counts["scheduler.legacyBrokenJobs"] != 0 || counts["scheduler.legacyFailingJobs"] != 1 || counts["scheduler.legacyRunningJobs"] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+
+	senders = collectMetricGroup(t, oracleLegacyJobs, sqlmock.NewRows(columns).
+		AddRow("1", 21, "HR", "purge_audit;", "N", 0, 0).
+		AddRow("1", 22, "HR", "refresh_totals;", "Y", 2, 0))
+	var events int
+	for _, sender := range senders {
+		if sender.event == nil {
+			continue
+		}
+		events++
+		if sender.event.Attributes["jobName"] != "22" || sender.event.Attributes["broken"] != "Y" {
+			t.Errorf("Unexpected event %+v", sender.event)
+		}
+	}
+	if events != 1 {
+		t.Errorf("Expected 1 event, got %d", events)
+	}
+}
+
+func TestLegacyJobs_NoJobs(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "JOB", "SCHEMA_USER", "WHAT", "BROKEN", "FAILURES", "RUNNING"}
+	senders := collectMetricGroup(t, oracleLegacyJobs, sqlmock.NewRows(columns).
+		AddRow("1", nil, nil, nil, nil, nil, nil))
+	if len(senders) != 3 {
+		t.Fatalf("Expected the 3 counts of the instance, got %d", len(senders))
+	}
+	for _, sender := range senders {
+		if sender.event != nil || sender.metric.value != 0 {
+			t.Errorf("Expected zero counts, got %+v", sender)
+		}
+	}
+}
+
+func TestLegacyJobs_SkippedFrom12c(t *testing.T) {
+	defer func() { oracleVersion = "" }()
+	oracleVersion = "19.0.0.0.0"
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	metricChan := make(chan newrelicMetricSender, 10)
+	oracleLegacyJobs.Collect(database.NewDBWrapper(sqlx.NewDb(db, "sqlmock")), &wg, metricChan)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if len(metricChan) != 0 {
+		t.Errorf("Expected no metrics on 19c, got %d", len(metricChan))
+	}
+}
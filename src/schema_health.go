@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+)
+
+// oracleMaintainedSchemas are the schemas created by Oracle components, excluded
+// from schema health on releases without the ORACLE_MAINTAINED column in dba_users
+var oracleMaintainedSchemas = []string{
+	"ANONYMOUS", "APEX_030200", "APEX_PUBLIC_USER", "APPQOSSYS", "AUDSYS", "CTXSYS",
+	"DBSNMP", "DIP", "DVF", "DVSYS", "EXFSYS", "FLOWS_FILES", "GSMADMIN_INTERNAL",
+	"GSMCATUSER", "GSMUSER", "LBACSYS", "MDDATA", "MDSYS", "MGMT_VIEW", "OJVMSYS",
+	"OLAPSYS", "ORACLE_OCM", "ORDDATA", "ORDPLUGINS", "ORDSYS", "OUTLN", "OWBSYS",
+	"OWBSYS_AUDIT", "SI_INFORMTN_SCHEMA", "SPATIAL_CSW_ADMIN_USR", "SPATIAL_WFS_ADMIN_USR",
+	"SYS", "SYSBACKUP", "SYSDG", "SYSKM", "SYSMAN", "SYSTEM", "WMSYS", "XDB", "XS$NULL",
+}
+
+// oracleMaintainedSchemaFilter returns the condition excluding Oracle maintained
+// schemas from the owner column field
+func oracleMaintainedSchemaFilter(field string) string {
+	filter := fmt.Sprintf(`%s NOT IN (%s)`, field, quotedSchemas(oracleMaintainedSchemas))
+
+	// ORACLE_MAINTAINED was added to dba_users in 12.1
+	if compareVersionPrefix(oracleVersion, "12.1") >= 0 {
+		filter += fmt.Sprintf(` AND %s IN (SELECT USERNAME FROM dba_users WHERE ORACLE_MAINTAINED = 'N')`, field)
+	}
+	return filter
+}
+
+// schemaHealthFilter returns the condition excluding Oracle maintained schemas and
+// the schemas of SCHEMA_HEALTH_EXCLUDE from the owner column field
+func schemaHealthFilter(field string) string {
+	filter := oracleMaintainedSchemaFilter(field)
+	if len(schemaHealthExclude) > 0 {
+		filter += fmt.Sprintf(` AND %s NOT IN (%s)`, field, quotedSchemas(schemaHealthExclude))
+	}
+	return filter
+}
+
+// quotedSchemas returns schemas as a list of SQL string literals
+func quotedSchemas(schemas []string) string {
+	quoted := make([]string, 0, len(schemas))
+	for _, schema := range schemas {
+		quoted = append(quoted, fmt.Sprintf(`'%s'`, strings.ReplaceAll(schema, "'", "''")))
+	}
+	return strings.Join(quoted, ",")
+}
+
+var oracleSchemaHealth = oracleMetricGroup{
+	name:       "schema_health",
+	entityType: "ora-schema",
+	slow:       true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			OWNER,
+			SUM(INVALID_OBJECTS) AS INVALID_OBJECTS,
+			SUM(UNUSABLE_INDEXES) AS UNUSABLE_INDEXES,
+			SUM(UNUSABLE_INDEX_PARTITIONS) AS UNUSABLE_INDEX_PARTITIONS,
+			SUM(STALE_STATISTICS) AS STALE_STATISTICS,
+			SUM(MISSING_STATISTICS) AS MISSING_STATISTICS
+		FROM (
+			SELECT OWNER, COUNT(*) AS INVALID_OBJECTS, 0 AS UNUSABLE_INDEXES, 0 AS UNUSABLE_INDEX_PARTITIONS, 0 AS STALE_STATISTICS, 0 AS MISSING_STATISTICS
+			FROM dba_objects
+			WHERE STATUS = 'INVALID'
+			GROUP BY OWNER
+			UNION ALL
+			SELECT OWNER, 0, COUNT(*), 0, 0, 0
+			FROM dba_indexes
+			WHERE STATUS = 'UNUSABLE'
+			GROUP BY OWNER
+			UNION ALL
+			SELECT INDEX_OWNER, 0, 0, COUNT(*), 0, 0
+			FROM dba_ind_partitions
+			WHERE STATUS = 'UNUSABLE'
+			GROUP BY INDEX_OWNER
+			UNION ALL
+			SELECT OWNER, 0, 0, 0,
+				SUM(CASE WHEN STALE_STATS = 'YES' THEN 1 ELSE 0 END),
+				SUM(CASE WHEN LAST_ANALYZED IS NULL THEN 1 ELSE 0 END)
+			FROM dba_tab_statistics
+			WHERE OBJECT_TYPE = 'TABLE'
+			GROUP BY OWNER
+		)
+		WHERE ` + schemaHealthFilter("OWNER") + `
+		GROUP BY OWNER`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "schema.invalidObjects",
+			identifier:    "INVALID_OBJECTS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "schema.unusableIndexes",
+			identifier:    "UNUSABLE_INDEXES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "schema.unusableIndexPartitions",
+			identifier:    "UNUSABLE_INDEX_PARTITIONS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "schema.tablesWithStaleStatistics",
+			identifier:    "STALE_STATISTICS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "schema.tablesWithoutStatistics",
+			identifier:    "MISSING_STATISTICS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{"schema": stringValue(row["OWNER"])}
+	}),
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+func Test_oracleMaintainedSchemaFilter(t *testing.T) {
+	defer func() { oracleVersion = "" }()
+
+	oracleVersion = "11.2.0.4.0"
+	filter := oracleMaintainedSchemaFilter("OWNER")
+	if !strings.Contains(filter, "'SYSTEM'") || strings.Contains(filter, "ORACLE_MAINTAINED") {
+		t.Errorf("Expected only the built-in exclude list on 11g, got %s", filter)
+	}
+
+	oracleVersion = "19.0.0.0.0"
+	if filter := oracleMaintainedSchemaFilter("OWNER"); !strings.Contains(filter, "ORACLE_MAINTAINED = 'N'") {
+		t.Errorf("Expected ORACLE_MAINTAINED to be used on 19c, got %s", filter)
+	}
+}
+
+func Test_schemaHealthFilter(t *testing.T) {
+	defer func() {
+		oracleVersion = ""
+		schemaHealthExclude = nil
+	}()
+
+	oracleVersion = "19.0.0.0.0"
+	schemaHealthExclude = []string{"APP_AUDIT", "O'NEIL"}
+	filter := schemaHealthFilter("OWNER")
+	if !strings.Contains(filter, "'SYSTEM'") || !strings.Contains(filter, "ORACLE_MAINTAINED = 'N'") {
+		t.Errorf("Expected the Oracle maintained schemas to stay excluded, got %s", filter)
+	}
+	if !strings.HasSuffix(filter, ` AND OWNER NOT IN ('APP_AUDIT','O''NEIL')`) {
+		t.Errorf("Expected the schemas of SCHEMA_HEALTH_EXCLUDE to be excluded too, got %s", filter)
+	}
+}
+
+func TestSchemaHealth(t *testing.T) {
+	senders := collectMetricGroup(t, oracleSchemaHealth, sqlmock.NewRows([]string{"OWNER", "INVALID_OBJECTS", "UNUSABLE_INDEXES", "UNUSABLE_INDEX_PARTITIONS", "STALE_STATISTICS", "MISSING_STATISTICS"}).
+		AddRow("HR", 2, 1, 0, 3, 4))
+
+	if len(senders) != 5 {
+		t.Fatalf("Expected 5 metrics, got %d", len(senders))
+	}
+	for _, sender := range senders {
+		if sender.metadata["schema"] != "HR" {
+			t.Errorf("Expected metric %s on schema HR, got %v", sender.metric.name, sender.metadata)
+		}
+	}
+}
+
+func Test_slowMetricGroupDue(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	slowMetricsInterval = time.Hour
+	defer func() {
+		stateStore = persist.NewInMemoryStore()
+		slowMetricsInterval = 0
+	}()
+
+	if !slowMetricGroupDue("schema_health") || !slowMetricGroupDue("schema_health") {
+		t.Error("Expected a slow group to be due until it runs successfully")
+	}
+	markSlowMetricGroupRun("schema_health")
+	if slowMetricGroupDue("schema_health") {
+		t.Error("Expected a slow group run less than an interval ago not to be due")
+	}
+
+	stateStore.Set(stateKey("slow-group", "schema_health"), time.Now().Add(-2*time.Hour).Unix())
+	if !slowMetricGroupDue("schema_health") {
+		t.Error("Expected a slow group run more than an interval ago to be due")
+	}
+}
+
+func TestSchemaHealth_FailedRunNotRecorded(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	slowMetricsInterval = time.Hour
+	defer func() {
+		stateStore = persist.NewInMemoryStore()
+		slowMetricsInterval = 0
+	}()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT.*").WillReturnError(errors.New("ORA-01013: user requested cancel of current operation"))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	metricChan := make(chan newrelicMetricSender, 10)
+	oracleSchemaHealth.Collect(database.NewDBWrapper(sqlx.NewDb(db, "sqlmock")), &wg, metricChan)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if !slowMetricGroupDue("schema_health") {
+		t.Error("Expected a failed slow group to be due in the next execution")
+	}
+}
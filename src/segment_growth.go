@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const segmentSample = "OracleSegmentSample"
+
+var (
+	segmentSizesKey      = stateKey("segments", "sizes")
+	segmentLastSnapIDKey = stateKey("segments", "last-snap-id")
+)
+
+var oracleSegments = oracleMetricGroup{
+	name: "segments",
+	slow: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Every application segment is read so small segments growing fast are ranked too.
+		// The segments of Oracle maintained schemas are left out, as their sizes are kept
+		// in the state store between runs
+		query := `
+		SELECT OWNER, SEGMENT_NAME, PARTITION_NAME, SEGMENT_TYPE, TABLESPACE_NAME, BYTES
+		FROM dba_segments
+		WHERE ` + oracleMaintainedSchemaFilter("OWNER") + inWhitelist("TABLESPACE_NAME", false, false)
+
+		if !args.SegmentGrowthFromAWR {
+			return query
+		}
+
+		// The Diagnostics Pack history adds up the space used by every snapshot of this
+		// database since the last run, leaving out AWR data imported from other databases.
+		// The first run only looks up the latest snapshot
+		snapFilter := "(SELECT MAX(SNAP_ID) FROM dba_hist_snapshot WHERE DBID = (SELECT DBID FROM v$database))"
+		if lastSnapID, ok := segmentLastSnapID(); ok {
+			snapFilter = strconv.FormatInt(lastSnapID, 10)
+		}
+		return fmt.Sprintf(`
+		SELECT
+			seg.OWNER, seg.SEGMENT_NAME, seg.PARTITION_NAME, seg.SEGMENT_TYPE, seg.TABLESPACE_NAME, seg.BYTES,
+			awr.GROWTH_BYTES,
+			(SELECT MAX(SNAP_ID) FROM dba_hist_snapshot WHERE DBID = (SELECT DBID FROM v$database)) AS MAX_SNAP_ID
+		FROM (%s
+		) seg
+		LEFT JOIN (
+			SELECT o.OWNER, o.OBJECT_NAME, o.SUBOBJECT_NAME, SUM(s.SPACE_USED_DELTA) AS GROWTH_BYTES
+			FROM dba_hist_seg_stat s
+			JOIN dba_hist_seg_stat_obj o
+				ON o.DBID = s.DBID AND o.TS# = s.TS# AND o.OBJ# = s.OBJ# AND o.DATAOBJ# = s.DATAOBJ#
+			WHERE s.DBID = (SELECT DBID FROM v$database) AND s.SNAP_ID > %s
+			GROUP BY o.OWNER, o.OBJECT_NAME, o.SUBOBJECT_NAME
+		) awr
+			ON awr.OWNER = seg.OWNER AND awr.OBJECT_NAME = seg.SEGMENT_NAME
+			AND NVL(awr.SUBOBJECT_NAME, ' ') = NVL(seg.PARTITION_NAME, ' ')`, query, snapFilter)
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "segment.sizeInBytes",
+			identifier:    "BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "segment.growthInBytes",
+			identifier:    "GROWTH_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: segmentGrowthGenerator,
+}
+
+// segment is a segment of dba_segments with its growth since the last run, if known
+type segment struct {
+	key       string
+	metadata  map[string]string
+	bytes     float64
+	growth    float64
+	hasGrowth bool
+}
+
+// segmentGrowthGenerator reports the SEGMENTS_TOP_N largest segments and the
+// SEGMENTS_TOP_N segments that grew the most since the last run, ranked over every
+// segment. Growth is computed from the sizes of every segment kept in the state store, or read from the Diagnostics Pack history
+// when SEGMENT_GROWTH_FROM_AWR is enabled
+func segmentGrowthGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	var previousSizes map[string]float64
+	if _, err := stateStore.Get(segmentSizesKey, &previousSizes); err != nil {
+		previousSizes = nil
+	}
+	_, hasLastSnapID := segmentLastSnapID()
+
+	var segments []*segment
+	sizes := make(map[string]float64)
+	maxSnapID := ""
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		s := &segment{metadata: segmentMetadata(rowMap)}
+		s.key = fmt.Sprintf("%s|%s|%s|%s", s.metadata["tablespace"], stringValue(rowMap["OWNER"]), stringValue(rowMap["SEGMENT_NAME"]), stringValue(rowMap["PARTITION_NAME"]))
+		s.bytes, _ = toFloat64(sanitizedValue(rowMap["BYTES"]))
+
+		if args.SegmentGrowthFromAWR {
+			maxSnapID = stringValue(rowMap["MAX_SNAP_ID"])
+			if hasLastSnapID {
+				s.growth, _ = toFloat64(sanitizedValue(rowMap["GROWTH_BYTES"]))
+				s.hasGrowth = true
+			}
+		} else if previous, ok := previousSizes[s.key]; ok {
+			s.growth = s.bytes - previous
+			s.hasGrowth = true
+		}
+
+		sizes[s.key] = s.bytes
+		segments = append(segments, s)
+	}
+
+	if args.SegmentGrowthFromAWR {
+		if snapID, err := strconv.ParseInt(maxSnapID, 10, 64); err == nil {
+			stateStore.Set(segmentLastSnapIDKey, snapID)
+		}
+	} else {
+		stateStore.Set(segmentSizesKey, sizes)
+	}
+
+	sizeMetric, growthMetric := metrics[0], metrics[1]
+	for _, s := range topSegments(segments, args.SegmentsTopN) {
+		if metricEnabled(sizeMetric) {
+			metricChan <- newrelicMetricSender{
+				metric:   &newrelicMetric{name: sizeMetric.name, metricType: sizeMetric.metricType, value: s.bytes},
+				metadata: s.metadata,
+			}
+		}
+		if s.hasGrowth && metricEnabled(growthMetric) {
+			metricChan <- newrelicMetricSender{
+				metric:   &newrelicMetric{name: growthMetric.name, metricType: growthMetric.metricType, value: s.growth},
+				metadata: s.metadata,
+			}
+		}
+	}
+
+	return nil
+}
+
+// segmentMetadata routes the metrics of a dba_segments row to a segment sample of its tablespace
+func segmentMetadata(row map[string]interface{}) map[string]string {
+	metadata := map[string]string{
+		"tablespace":                            stringValue(row["TABLESPACE_NAME"]),
+		sampleMetadataKey:                       segmentSample,
+		dimensionMetadataPrefix + "owner":       stringValue(row["OWNER"]),
+		dimensionMetadataPrefix + "segmentName": stringValue(row["SEGMENT_NAME"]),
+		dimensionMetadataPrefix + "segmentType": stringValue(row["SEGMENT_TYPE"]),
+	}
+	if partition := stringValue(row["PARTITION_NAME"]); partition != "" {
+		metadata[dimensionMetadataPrefix+"partitionName"] = partition
+	}
+	return metadata
+}
+
+// topSegments returns the n largest segments followed by the n segments that grew
+// the most and are not among the largest. Every segment is returned when n is not positive
+func topSegments(segments []*segment, n int) []*segment {
+	if n <= 0 || len(segments) <= n {
+		return segments
+	}
+
+	bySize := append([]*segment(nil), segments...)
+	sort.SliceStable(bySize, func(i, j int) bool { return bySize[i].bytes > bySize[j].bytes })
+
+	byGrowth := append([]*segment(nil), segments...)
+	sort.SliceStable(byGrowth, func(i, j int) bool { return byGrowth[i].growth > byGrowth[j].growth })
+
+	top := bySize[:n:n]
+	selected := make(map[string]bool, 2*n)
+	for _, s := range top {
+		selected[s.key] = true
+	}
+	for _, s := range byGrowth[:n] {
+		if s.hasGrowth && s.growth > 0 && !selected[s.key] {
+			top = append(top, s)
+			selected[s.key] = true
+		}
+	}
+	return top
+}
+
+// segmentLastSnapID returns the latest AWR snapshot ID seen by a previous run
+func segmentLastSnapID() (int64, bool) {
+	var snapID int64
+	if _, err := stateStore.Get(segmentLastSnapIDKey, &snapID); err != nil {
+		return 0, false
+	}
+	return snapID, true
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+func Test_topSegments(t *testing.T) {
+	segments := []*segment{
+		{key: "a", bytes: 100},
+		{key: "b", bytes: 50, growth: 5, hasGrowth: true},
+		{key: "c", bytes: 10, growth: 8, hasGrowth: true},
+		{key: "d", bytes: 20, growth: -4, hasGrowth: true},
+	}
+
+	var keys []string
+	for _, s := range topSegments(segments, 1) {
+		keys = append(keys, s.key)
+	}
+	if strings.Join(keys, ",") != "a,c" {
+		t.Errorf("Expected the largest segment and the fastest growing one, got %v", keys)
+	}
+
+	if top := topSegments(segments, 0); len(top) != 4 {
+		t.Errorf("Expected every segment without a limit, got %d", len(top))
+	}
+}
+
+func TestSegments(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	args = argumentList{SegmentsTopN: 1}
+	defer func() {
+		stateStore = persist.NewInMemoryStore()
+		args = argumentList{}
+	}()
+
+	query := oracleSegments.sqlQuery(oracleSegments.metrics)
+	if strings.Contains(query, "ROWNUM") {
+		t.Errorf("Expected growth to be ranked over every segment, got %s", query)
+	}
+	if !strings.Contains(query, "WHERE OWNER NOT IN ('ANONYMOUS'") {
+		t.Errorf("Expected the segments of Oracle maintained schemas to be left out, got %s", query)
+	}
+
+	columns := []string{"OWNER", "SEGMENT_NAME", "PARTITION_NAME", "SEGMENT_TYPE", "TABLESPACE_NAME", "BYTES"}
+
+	senders := collectMetricGroup(t, oracleSegments, sqlmock.NewRows(columns).
+		AddRow("HR", "EMPLOYEES", nil, "TABLE", "USERS", 1000).
+		AddRow("HR", "AUDIT_LOG", "P2024", "TABLE PARTITION", "USERS", 200))
+	if len(senders) != 1 || senders[0].metric.name != "segment.sizeInBytes" {
+		t.Fatalf("Expected only the size of the largest segment on the first run, got %+v", senders)
+	}
+	if metadata := senders[0].metadata; metadata["tablespace"] != "USERS" || metadata[sampleMetadataKey] != segmentSample || metadata["dimension.segmentName"] != "EMPLOYEES" {
+		t.Errorf("Unexpected metadata %v", metadata)
+	}
+
+	senders = collectMetricGroup(t, oracleSegments, sqlmock.NewRows(columns).
+		AddRow("HR", "EMPLOYEES", nil, "TABLE", "USERS", 1000).
+		AddRow("HR", "AUDIT_LOG", "P2024", "TABLE PARTITION", "USERS", 700))
+
+	growth := make(map[string]interface{})
+	for _, sender := range senders {
+		if sender.metric.name == "segment.growthInBytes" {
+			growth[sender.metadata["dimension.segmentName"]] = sender.metric.value
+		}
+	}
+	if growth["EMPLOYEES"] != float64(0) || growth["AUDIT_LOG"] != float64(500) {
+		t.Errorf("Expected the growth of the largest and the fastest growing segments, got %v", growth)
+	}
+}
+
+func TestSegments_AWR(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	args = argumentList{SegmentGrowthFromAWR: true}
+	defer func() {
+		stateStore = persist.NewInMemoryStore()
+		args = argumentList{}
+	}()
+
+	query := oracleSegments.sqlQuery(oracleSegments.metrics)
+	if !strings.Contains(query, "s.SNAP_ID > (SELECT MAX(SNAP_ID)") {
+		t.Errorf("Expected the first run to skip past snapshots, got %s", query)
+	}
+	if !strings.Contains(query, "s.DBID = (SELECT DBID FROM v$database)") {
+		t.Errorf("Expected only the AWR history of this database, got %s", query)
+	}
+
+	columns := []string{"OWNER", "SEGMENT_NAME", "PARTITION_NAME", "SEGMENT_TYPE", "TABLESPACE_NAME", "BYTES", "GROWTH_BYTES", "MAX_SNAP_ID"}
+	collectMetricGroup(t, oracleSegments, sqlmock.NewRows(columns).
+		AddRow("HR", "EMPLOYEES", nil, "TABLE", "USERS", 1000, nil, 120))
+
+	if query := oracleSegments.sqlQuery(oracleSegments.metrics); !strings.Contains(query, "s.SNAP_ID > 120") {
+		t.Errorf("Expected the query to start after the last snapshot, got %s", query)
+	}
+
+	senders := collectMetricGroup(t, oracleSegments, sqlmock.NewRows(columns).
+		AddRow("HR", "EMPLOYEES", nil, "TABLE", "USERS", 1300, 300, 122))
+	if len(senders) != 2 || senders[1].metric.value != float64(300) {
+		t.Errorf("Expected the size and the growth from AWR, got %+v", senders)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+)
+
+// serviceSample is the sample type of the metrics of a database service on an instance
+const serviceSample = "OracleServiceSample"
+
+// serviceMetadata routes the metrics of a row with INST_ID and SERVICE_NAME columns
+// to the service sample of the instance
+func serviceMetadata(row map[string]interface{}) map[string]string {
+	return map[string]string{
+		"service":         stringValue(row["SERVICE_NAME"]),
+		sampleMetadataKey: serviceSample,
+		dimensionMetadataPrefix + instanceDimension: getInstanceIDString(row["INST_ID"]),
+	}
+}
+
+var oracleServices = oracleMetricGroup{
+	name:       "services",
+	entityType: "ora-service",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Group 6 holds the service metrics over the last 60 seconds, group 10 only the last 5
+		return `
+		SELECT
+			s.INST_ID,
+			s.NAME AS SERVICE_NAME,
+			s.NETWORK_NAME,
+			s.GOAL,
+			s.CLB_GOAL,
+			s.BLOCKED,
+			m.ELAPSEDPERCALL,
+			m.CPUPERCALL,
+			m.DBTIMEPERSEC,
+			m.CALLSPERSEC
+		FROM gv$active_services s
+		LEFT JOIN gv$servicemetric m
+			ON m.INST_ID = s.INST_ID AND m.SERVICE_NAME = s.NAME AND m.GROUP_ID = 6
+		WHERE s.NAME NOT LIKE 'SYS$%'`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "service.networkName",
+			identifier:    "NETWORK_NAME",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "service.goal",
+			identifier:    "GOAL",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "service.connectionLoadBalancingGoal",
+			identifier:    "CLB_GOAL",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "service.blocked",
+			identifier:    "BLOCKED",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+		{
+			name:          "service.elapsedTimePerCallInMicroseconds",
+			identifier:    "ELAPSEDPERCALL",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "service.cpuTimePerCallInMicroseconds",
+			identifier:    "CPUPERCALL",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "service.dbTimeCentisecondsPerSecond",
+			identifier:    "DBTIMEPERSEC",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "service.callsPerSecond",
+			identifier:    "CALLSPERSEC",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(serviceMetadata),
+}
+
+var oracleServiceStats = oracleMetricGroup{
+	name:       "service_stats",
+	entityType: "ora-service",
+	optIn:      true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		query := `
+		SELECT INST_ID, SERVICE_NAME, STAT_NAME AS NAME, VALUE
+		FROM gv$service_stats
+		WHERE SERVICE_NAME NOT LIKE 'SYS$%' AND`
+		query += inMetrics("STAT_NAME", metrics)
+		return query
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "service.userCallsPerSecond",
+			identifier:    "user calls",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "service.userCommitsPerSecond",
+			identifier:    "user commits",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "service.userRollbacksPerSecond",
+			identifier:    "user rollbacks",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "service.dbTimeInMicroseconds",
+			identifier:    "DB time",
+			metricType:    metric.DELTA,
+			defaultMetric: false,
+		},
+		{
+			name:          "service.dbCpuInMicroseconds",
+			identifier:    "DB CPU",
+			metricType:    metric.DELTA,
+			defaultMetric: false,
+		},
+		{
+			name:          "service.physicalReadsPerSecond",
+			identifier:    "physical reads",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+		{
+			name:          "service.logonsPerSecond",
+			identifier:    "logons cumulative",
+			metricType:    metric.RATE,
+			defaultMetric: false,
+		},
+	},
+
+	metricsGenerator: keyedRowMetricsGenerator(serviceMetadata),
+}
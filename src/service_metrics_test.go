@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+)
+
+func TestServiceMetrics(t *testing.T) {
+	if query := oracleServices.sqlQuery(oracleServices.metrics); !strings.Contains(query, "m.GROUP_ID = 6") {
+		t.Errorf("Expected the 60 second service metrics, got %s", query)
+	}
+
+	columns := []string{"INST_ID", "SERVICE_NAME", "NETWORK_NAME", "GOAL", "CLB_GOAL", "BLOCKED", "ELAPSEDPERCALL", "CPUPERCALL", "DBTIMEPERSEC", "CALLSPERSEC"}
+
+	senders := collectMetricGroup(t, oracleServices, sqlmock.NewRows(columns).
+		AddRow(2, "orders", "orders.example.com", "NONE", "LONG", "NO", 1500.5, 800.25, 12.5, 40.0).
+		AddRow(2, "reports", "reports.example.com", "NONE", "LONG", "NO", nil, nil, nil, nil))
+
+	reported := make(map[string]int)
+	for _, sender := range senders {
+		service := sender.metadata["service"]
+		reported[service]++
+		if sender.metadata[sampleMetadataKey] != serviceSample || sender.metadata[dimensionMetadataPrefix+instanceDimension] != "2" {
+			t.Errorf("Unexpected metadata %v", sender.metadata)
+		}
+	}
+
+	if reported["orders"] != 8 {
+		t.Errorf("Expected 8 metrics for service orders, got %d", reported["orders"])
+	}
+	if reported["reports"] != 4 {
+		t.Errorf("Expected only the 4 attributes of service reports without service metrics, got %d", reported["reports"])
+	}
+}
+
+func TestServiceStats(t *testing.T) {
+	args = argumentList{ExtendedMetrics: true}
+	defer func() { args = argumentList{} }()
+
+	senders := collectMetricGroup(t, oracleServiceStats, sqlmock.NewRows([]string{"INST_ID", "SERVICE_NAME", "NAME", "VALUE"}).
+		AddRow(1, "orders", "user calls", 1000).
+		AddRow(1, "orders", "unknown statistic", 5))
+
+	if len(senders) != 1 {
+		t.Fatalf("Expected 1 metric, got %d", len(senders))
+	}
+	if sender := senders[0]; sender.metric.name != "service.userCallsPerSecond" || sender.metric.value != int64(1000) || sender.metadata["service"] != "orders" {
+		t.Errorf("Unexpected metric %+v with metadata %v", sender.metric, sender.metadata)
+	}
+}
+
+func TestPopulateMetrics_Services(t *testing.T) {
+	args = argumentList{
+		Hostname:    "testhost",
+		Port:        "1234",
+		ServiceName: "testServiceName",
+	}
+	defer func() { args = argumentList{} }()
+
+	i, _ := integration.New("oracletest", "0.0.1")
+	metricChan := make(chan newrelicMetricSender, 1)
+	metricChan <- newrelicMetricSender{
+		metric:   &newrelicMetric{name: "service.callsPerSecond", value: 40.0, metricType: metric.GAUGE},
+		metadata: serviceMetadata(map[string]interface{}{"INST_ID": int64(1), "SERVICE_NAME": "orders"}),
+	}
+	close(metricChan)
+
+	populateMetrics(metricChan, i, map[string]string{"1": "one"})
+
+	marshalled, err := i.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedJSON := `{"name":"oracletest","protocol_version":"3","integration_version":"0.0.1","data":[{"entity":{"name":"orders","type":"ora-service","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[{"displayName":"orders","entityName":"ora-service:orders","event_type":"OracleServiceSample","instance":"one","reportingEndpoint":"testhost:1234","service.callsPerSecond":40}],"inventory":{},"events":[]}]}`
+	if string(marshalled) != expectedJSON {
+		t.Errorf("Expected %s, got %s", expectedJSON, marshalled)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const (
+	sessionSample = "OracleSessionSample"
+	// sessionBreakdownDimension names the gv$session column a session sample is bucketed by
+	sessionBreakdownDimension = "breakdown"
+	// sessionIdleDimension is the pseudo dimension of the inactive idle session count
+	sessionIdleDimension = "idle"
+	sessionOtherBucket   = "other"
+	sessionUnknownBucket = "unknown"
+)
+
+// sessionDimensions maps the gv$session columns sessions are counted by to the
+// attribute they are reported as
+var sessionDimensions = []struct {
+	column    string
+	attribute string
+}{
+	{"STATUS", "status"},
+	{"TYPE", "type"},
+	{"USERNAME", "username"},
+	{"SERVICE_NAME", "serviceName"},
+	{"MACHINE", "machine"},
+	{"PROGRAM", "program"},
+}
+
+var oracleSessions = oracleMetricGroup{
+	name: "sessions",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		queries := make([]string, 0, len(sessionDimensions)+1)
+		for _, dimension := range sessionDimensions {
+			queries = append(queries, fmt.Sprintf(`
+		SELECT INST_ID, '%s' AS DIMENSION, %s AS VALUE, COUNT(*) AS SESSIONS
+		FROM gv$session
+		GROUP BY INST_ID, %s`, dimension.attribute, dimension.column, dimension.column))
+		}
+
+		queries = append(queries, fmt.Sprintf(`
+		SELECT INST_ID, '%s' AS DIMENSION, NULL AS VALUE,
+			SUM(CASE WHEN STATUS = 'INACTIVE' AND TYPE = 'USER' AND LAST_CALL_ET > %d THEN 1 ELSE 0 END) AS SESSIONS
+		FROM gv$session
+		GROUP BY INST_ID`, sessionIdleDimension, args.SessionIdleMinutes*60))
+
+		return strings.Join(queries, "\n\t\tUNION ALL")
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "session.count",
+			identifier:    "SESSIONS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "db.idleInactiveSessionCount",
+			identifier:    sessionIdleDimension,
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: sessionMetricsGenerator,
+}
+
+// sessionBucket is the number of sessions of an instance with a value of a dimension
+type sessionBucket struct {
+	value    string
+	sessions float64
+}
+
+// sessionMetricsGenerator reports the session count of every dimension bucket on a
+// session sample of its instance. Only the SESSIONS_TOP_N largest buckets of each
+// dimension are reported, the rest are added up in an "other" bucket
+func sessionMetricsGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	sessionCount := metricByIdentifier(metrics, "SESSIONS")
+	idleCount := metricByIdentifier(metrics, sessionIdleDimension)
+
+	type bucketKey struct {
+		instanceID string
+		dimension  string
+	}
+	var keys []bucketKey
+	buckets := make(map[bucketKey][]sessionBucket)
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		instanceID := getInstanceIDString(rowMap["INST_ID"])
+		dimension := stringValue(rowMap["DIMENSION"])
+		sessions, _ := toFloat64(sanitizedValue(rowMap["SESSIONS"]))
+
+		if dimension == sessionIdleDimension {
+			if idleCount != nil && metricEnabled(idleCount) {
+				metricChan <- newrelicMetricSender{
+					metric:   &newrelicMetric{name: idleCount.name, metricType: idleCount.metricType, value: sessions},
+					metadata: map[string]string{"instanceID": instanceID},
+				}
+			}
+			continue
+		}
+
+		value := stringValue(rowMap["VALUE"])
+		if value == "" {
+			value = sessionUnknownBucket
+		}
+
+		key := bucketKey{instanceID, dimension}
+		if _, ok := buckets[key]; !ok {
+			keys = append(keys, key)
+		}
+		buckets[key] = append(buckets[key], sessionBucket{value, sessions})
+	}
+
+	if sessionCount == nil || !metricEnabled(sessionCount) {
+		return nil
+	}
+
+	for _, key := range keys {
+		for _, bucket := range topSessionBuckets(buckets[key], args.SessionsTopN) {
+			metricChan <- newrelicMetricSender{
+				metric: &newrelicMetric{name: sessionCount.name, metricType: sessionCount.metricType, value: bucket.sessions},
+				metadata: map[string]string{
+					"instanceID":      key.instanceID,
+					sampleMetadataKey: sessionSample,
+					dimensionMetadataPrefix + sessionBreakdownDimension: key.dimension,
+					dimensionMetadataPrefix + key.dimension:             bucket.value,
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+// topSessionBuckets returns the n buckets with the most sessions, followed by an
+// "other" bucket adding up the rest. Every bucket is returned when n is not positive
+func topSessionBuckets(buckets []sessionBucket, n int) []sessionBucket {
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if buckets[i].sessions != buckets[j].sessions {
+			return buckets[i].sessions > buckets[j].sessions
+		}
+		return buckets[i].value < buckets[j].value
+	})
+
+	if n <= 0 || len(buckets) <= n {
+		return buckets
+	}
+
+	other := sessionBucket{value: sessionOtherBucket}
+	for _, bucket := range buckets[n:] {
+		other.sessions += bucket.sessions
+	}
+	return append(buckets[:n:n], other)
+}
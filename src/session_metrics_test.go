@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_topSessionBuckets(t *testing.T) {
+	buckets := []sessionBucket{
+		{"APP", 2},
+		{"BATCH", 5},
+		{"HR", 1},
+		{"SCOTT", 2},
+	}
+
+	expected := []sessionBucket{
+		{"BATCH", 5},
+		{"APP", 2},
+		{"other", 3},
+	}
+	if got := topSessionBuckets(buckets, 2); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	if got := topSessionBuckets(buckets, 0); len(got) != 4 {
+		t.Errorf("Expected every bucket without a limit, got %+v", got)
+	}
+}
+
+func TestSessionMetrics(t *testing.T) {
+	args = argumentList{SessionsTopN: 1, SessionIdleMinutes: 15}
+	defer func() { args = argumentList{} }()
+
+	if query := oracleSessions.sqlQuery(oracleSessions.metrics); !strings.Contains(query, "LAST_CALL_ET > 900") {
+		t.Errorf("Expected the idle threshold in seconds in query %s", query)
+	}
+
+	senders := collectMetricGroup(t, oracleSessions, sqlmock.NewRows([]string{"INST_ID", "DIMENSION", "VALUE", "SESSIONS"}).
+		AddRow(1, "status", "ACTIVE", 3).
+		AddRow(1, "status", "INACTIVE", 10).
+		AddRow(1, "username", nil, 40).
+		AddRow(1, "idle", nil, 7))
+
+	var got []map[string]interface{}
+	for _, sender := range senders {
+		entry := map[string]interface{}{"name": sender.metric.name, "value": sender.metric.value}
+		for key, value := range sender.metadata {
+			entry[key] = value
+		}
+		got = append(got, entry)
+	}
+
+	expected := []map[string]interface{}{
+		{"name": "db.idleInactiveSessionCount", "value": float64(7), "instanceID": "1"},
+		{"name": "session.count", "value": float64(10), "instanceID": "1", "sample": "OracleSessionSample", "dimension.breakdown": "status", "dimension.status": "INACTIVE"},
+		{"name": "session.count", "value": float64(3), "instanceID": "1", "sample": "OracleSessionSample", "dimension.breakdown": "status", "dimension.status": "other"},
+		{"name": "session.count", "value": float64(40), "instanceID": "1", "sample": "OracleSessionSample", "dimension.breakdown": "username", "dimension.username": "unknown"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestSessionMetrics_MetricOrder(t *testing.T) {
+	args = argumentList{SessionsTopN: 10}
+	defer func() { args = argumentList{} }()
+
+	group := oracleSessions
+	group.metrics = []*oracleMetric{oracleSessions.metrics[1], oracleSessions.metrics[0]}
+
+	senders := collectMetricGroup(t, group, sqlmock.NewRows([]string{"INST_ID", "DIMENSION", "VALUE", "SESSIONS"}).
+		AddRow(1, "status", "ACTIVE", 3).
+		AddRow(1, "idle", nil, 7))
+
+	names := make(map[string]float64)
+	for _, sender := range senders {
+		names[sender.metric.name] = sender.metric.value.(float64)
+	}
+	expected := map[string]float64{"db.idleInactiveSessionCount": 7, "session.count": 3}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestSessionMetrics_EmptyResult(t *testing.T) {
+	if senders := collectMetricGroup(t, oracleSessions, sqlmock.NewRows([]string{"INST_ID", "DIMENSION", "VALUE", "SESSIONS"})); len(senders) != 0 {
+		t.Errorf("Expected no metrics, got %d", len(senders))
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const sgaComponentSample = "OracleSGAComponentSample"
+
+// sgaComponentMetadata routes the metrics of a SGA component to its sample
+func sgaComponentMetadata(row map[string]interface{}) map[string]string {
+	return map[string]string{
+		"instanceID":                          getInstanceIDString(row["INST_ID"]),
+		sampleMetadataKey:                     sgaComponentSample,
+		dimensionMetadataPrefix + "component": stringValue(row["COMPONENT"]),
+	}
+}
+
+// oracleSGAComponents sends a sample per component whose sizes only change with a
+// resize, which sga_resize_operations reports, so it runs as a slow group
+var oracleSGAComponents = oracleMetricGroup{
+	name: "sga_components",
+	slow: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			INST_ID,
+			COMPONENT,
+			CURRENT_SIZE,
+			MIN_SIZE,
+			MAX_SIZE,
+			USER_SPECIFIED_SIZE,
+			GRANULE_SIZE,
+			LAST_OPER_TYPE
+		FROM gv$sga_dynamic_components`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "sga.component.currentSizeInBytes",
+			identifier:    "CURRENT_SIZE",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "sga.component.minSizeInBytes",
+			identifier:    "MIN_SIZE",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "sga.component.maxSizeInBytes",
+			identifier:    "MAX_SIZE",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "sga.component.userSpecifiedSizeInBytes",
+			identifier:    "USER_SPECIFIED_SIZE",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "sga.component.granuleSizeInBytes",
+			identifier:    "GRANULE_SIZE",
+			metricType:    metric.GAUGE,
+			defaultMetric: false,
+		},
+		{
+			name:          "sga.component.lastOperationType",
+			identifier:    "LAST_OPER_TYPE",
+			metricType:    metric.ATTRIBUTE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(sgaComponentMetadata),
+}
+
+var oracleSGAResizeOperations = oracleMetricGroup{
+	name: "sga_resize_operations",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		// Start times are only compared with the ones of previous runs, so the time zone
+		// of the database server doesn't matter
+		return `
+		SELECT
+			c.INST_ID,
+			c.COMPONENT,
+			o.OPER_TYPE,
+			o.FINAL_SIZE - o.INITIAL_SIZE AS SIZE_CHANGE,
+			(o.START_TIME - DATE '1970-01-01') * 86400 AS START_TIME
+		FROM gv$sga_dynamic_components c
+		LEFT JOIN gv$sga_resize_ops o
+			ON o.INST_ID = c.INST_ID AND o.COMPONENT = c.COMPONENT
+			AND o.STATUS = 'COMPLETE' AND o.OPER_TYPE IN ('GROW', 'SHRINK')
+		ORDER BY c.INST_ID, c.COMPONENT, o.START_TIME`
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "sga.component.resizeOperations",
+			identifier:    "RESIZE_OPERATIONS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "sga.component.growOperations",
+			identifier:    "GROW_OPERATIONS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "sga.component.shrinkOperations",
+			identifier:    "SHRINK_OPERATIONS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "sga.component.lastGrowInBytes",
+			identifier:    "LAST_GROW",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "sga.component.lastShrinkInBytes",
+			identifier:    "LAST_SHRINK",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "sga.resizeOperations",
+			identifier:    "TOTAL_RESIZE_OPERATIONS",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: sgaResizeOperationsGenerator,
+}
+
+// sgaComponentResizes are the resize operations of a SGA component since the previous run
+type sgaComponentResizes struct {
+	metadata map[string]string
+	values   map[string]interface{}
+	grows    int
+	shrinks  int
+}
+
+// sgaResizeOperationsGenerator counts the completed grow and shrink operations of every
+// SGA component since the previous run, with the size change of the last ones, and the
+// operations of every instance. The start time of the latest operation is kept per
+// instance in the state store, so nothing is reported on the first run
+func sgaResizeOperationsGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	var instanceIDs []string
+	var components []*sgaComponentResizes
+	byKey := make(map[string]*sgaComponentResizes)
+	previousStart := make(map[string]float64)
+	latestStart := make(map[string]float64)
+	seen := make(map[string]bool)
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		instanceID := getInstanceIDString(rowMap["INST_ID"])
+		if _, ok := latestStart[instanceID]; !ok {
+			instanceIDs = append(instanceIDs, instanceID)
+			var start float64
+			_, err := stateStore.Get(stateKey("sga-resize-operations", instanceID), &start)
+			seen[instanceID] = err == nil
+			previousStart[instanceID] = start
+			latestStart[instanceID] = start
+		}
+
+		key := instanceID + counterKeySeparator + stringValue(rowMap["COMPONENT"])
+		component, ok := byKey[key]
+		if !ok {
+			component = &sgaComponentResizes{metadata: sgaComponentMetadata(rowMap), values: make(map[string]interface{})}
+			byKey[key] = component
+			components = append(components, component)
+		}
+
+		start, ok := toFloat64(sanitizedValue(rowMap["START_TIME"]))
+		if !ok {
+			continue
+		}
+		if start > latestStart[instanceID] {
+			latestStart[instanceID] = start
+		}
+		if start <= previousStart[instanceID] {
+			continue
+		}
+
+		sizeChange, _ := toFloat64(sanitizedValue(rowMap["SIZE_CHANGE"]))
+		switch stringValue(rowMap["OPER_TYPE"]) {
+		case "GROW":
+			component.grows++
+			component.values["LAST_GROW"] = sizeChange
+		case "SHRINK":
+			component.shrinks++
+			component.values["LAST_SHRINK"] = -sizeChange
+		}
+	}
+
+	totals := make(map[string]int)
+	for _, component := range components {
+		instanceID := component.metadata["instanceID"]
+		if !seen[instanceID] {
+			continue
+		}
+		totals[instanceID] += component.grows + component.shrinks
+
+		component.values["RESIZE_OPERATIONS"] = component.grows + component.shrinks
+		component.values["GROW_OPERATIONS"] = component.grows
+		component.values["SHRINK_OPERATIONS"] = component.shrinks
+		for _, metric := range metrics {
+			if value, ok := component.values[metric.identifier]; ok && metricEnabled(metric) {
+				metricChan <- newrelicMetricSender{
+					metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: value},
+					metadata: component.metadata,
+				}
+			}
+		}
+	}
+
+	for _, instanceID := range instanceIDs {
+		stateStore.Set(stateKey("sga-resize-operations", instanceID), latestStart[instanceID])
+		if !seen[instanceID] {
+			continue
+		}
+
+		for _, metric := range metrics {
+			if metric.identifier == "TOTAL_RESIZE_OPERATIONS" && metricEnabled(metric) {
+				metricChan <- newrelicMetricSender{
+					metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: totals[instanceID]},
+					metadata: map[string]string{"instanceID": instanceID},
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+func TestSGAResizeOperations(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "COMPONENT", "OPER_TYPE", "SIZE_CHANGE", "START_TIME"}
+	senders := collectMetricGroup(t, oracleSGAResizeOperations, sqlmock.NewRows(columns).
+		AddRow("1", "DEFAULT buffer cache", "GROW", 16777216, 1700000000).
+		AddRow("1", "shared pool", nil, nil, nil))
+	if len(senders) != 0 {
+		t.Fatalf("Expected no metrics on the first run, got %d", len(senders))
+	}
+
+	senders = collectMetricGroup(t, oracleSGAResizeOperations, sqlmock.NewRows(columns).
+		AddRow("1", "DEFAULT buffer cache", "GROW", 16777216, 1700000000).
+		AddRow("1", "DEFAULT buffer cache", "SHRINK", -33554432, 1700000600).
+		AddRow("1", "shared pool", "GROW", 16777216, 1700000300).
+		AddRow("1", "shared pool", "GROW", 33554432, 1700000600))
+
+	components := make(map[string]map[string]interface{})
+	var total interface{}
+	for _, sender := range senders {
+		if sender.metadata[sampleMetadataKey] != sgaComponentSample {
+			total = sender.metric.value
+			continue
+		}
+		component := sender.metadata[dimensionMetadataPrefix+"component"]
+		if components[component] == nil {
+			components[component] = make(map[string]interface{})
+		}
+		components[component][sender.metric.name] = sender.metric.value
+	}
+
+	cache := components["DEFAULT buffer cache"]
+	if cache["sga.component.resizeOperations"] != 1 || cache["sga.component.shrinkOperations"] != 1 || cache["sga.component.lastShrinkInBytes"] != 33554432.0 {
+		t.Errorf("Unexpected buffer cache metrics %v", cache)
+	}
+	if _, ok := cache["sga.component.lastGrowInBytes"]; ok {
+		t.Errorf("Expected the grow of the first run to be left out, got %v", cache)
+	}
+
+	pool := components["shared pool"]
+	if pool["sga.component.growOperations"] != 2 || pool["sga.component.lastGrowInBytes"] != 33554432.0 {
+		t.Errorf("Unexpected shared pool metrics %v", pool)
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 resize operations on the instance, got %v", total)
+	}
+
+	senders = collectMetricGroup(t, oracleSGAResizeOperations, sqlmock.NewRows(columns).
+		AddRow("1", "shared pool", "GROW", 33554432, 1700000600))
+	for _, sender := range senders {
+		if sender.metric.name == "sga.resizeOperations" && sender.metric.value != 0 {
+			t.Errorf("Expected no new resize operations, got %v", sender.metric.value)
+		}
+	}
+}
+
+func TestSGAResizeOperations_NullStartTime(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "COMPONENT", "OPER_TYPE", "SIZE_CHANGE", "START_TIME"}
+	collectMetricGroup(t, oracleSGAResizeOperations, sqlmock.NewRows(columns).
+		AddRow("1", "shared pool", "GROW", 16777216, 1700000000))
+
+	senders := collectMetricGroup(t, oracleSGAResizeOperations, sqlmock.NewRows(columns).
+		AddRow("1", "shared pool", "GROW", 16777216, nil))
+	for _, sender := range senders {
+		if sender.metric.name == "sga.resizeOperations" && sender.metric.value != 0 {
+			t.Errorf("Expected operations without a start time to be left out, got %v", sender.metric.value)
+		}
+		if sender.metric.name == "sga.component.lastGrowInBytes" {
+			t.Errorf("Expected no last grow from an operation without a start time, got %v", sender.metric.value)
+		}
+	}
+
+	var start float64
+	if _, err := stateStore.Get(stateKey("sga-resize-operations", "1"), &start); err != nil || start != 1700000000 {
+		t.Errorf("Expected the latest start time to be kept, got %v", start)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+// stateStoreTTL is how long values are kept in the state store. It is much longer
+// than the SDK metrics cache since it holds schedules and daily history
+const stateStoreTTL = 30 * 24 * time.Hour
+
+// stateStore keeps values between integration runs, such as the last execution of
+// custom queries. It is replaced by a file store in main
+var stateStore = persist.NewInMemoryStore()
+
+// openStateStore opens the file backed state store of the monitored database
+func openStateStore() (persist.Storer, error) {
+	return persist.NewFileStore(persist.TmpPath(args.TempDir, stateStoreName()), log.NewStdErr(args.Verbose), stateStoreTTL)
+}
+
+// stateStoreName returns a file name unique to the monitored database, so
+// several integration instances can share the same temp directory
+func stateStoreName() string {
+	target := args.ConnectionString
+	if target == "" {
+		target = fmt.Sprintf("%s:%s/%s", args.Hostname, args.Port, args.ServiceName)
+	}
+	sum := sha256.Sum256([]byte(target + "|" + args.Username))
+	return fmt.Sprintf("%s-state-%x", integrationName, sum[:8])
+}
+
+// stateKey returns a store key for value, hashed so it is a valid file name
+func stateKey(prefix string, value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("%s-%x", prefix, sum[:8])
+}
+
+// counterSnapshot is the value of cumulative counters at a point in time
+type counterSnapshot struct {
+	Time     int64              `json:"time"`
+	Counters map[string]float64 `json:"counters"`
+}
+
+// counterDeltas replaces the counters kept under key with counters and returns how
+// much every counter increased since the previous run, and the seconds elapsed since
+// then. Counters seen for the first time or that went down, after an instance restart,
+// have no delta
+func counterDeltas(key string, counters map[string]float64, now time.Time) (map[string]float64, float64) {
+	var previous counterSnapshot
+	_, err := stateStore.Get(key, &previous)
+	stateStore.Set(key, counterSnapshot{Time: now.UnixMilli(), Counters: counters})
+
+	elapsed := float64(now.UnixMilli()-previous.Time) / 1000
+	if err != nil || elapsed <= 0 {
+		return nil, 0
+	}
+
+	deltas := make(map[string]float64, len(counters))
+	for name, value := range counters {
+		if before, ok := previous.Counters[name]; ok && value >= before {
+			deltas[name] = value - before
+		}
+	}
+	return deltas, elapsed
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+// trackedState is a value of an entity whose changes between runs are reported as events
+type trackedState struct {
+	// metadata routes the event to its entity, like the metadata of metrics
+	metadata map[string]string
+	// key identifies the value within its metric group
+	key string
+	// name is reported as the state attribute of the event
+	name    string
+	value   string
+	summary string
+	// attributes are added to the event
+	attributes map[string]interface{}
+}
+
+// sendStateChangeEvents compares states with the values persisted by the previous run
+// of the group and sends an event for each of them that changed. Nothing is reported
+// the first time a value is seen
+func sendStateChangeEvents(group string, states []trackedState, metricChan chan<- newrelicMetricSender) {
+	key := stateKey("state-events", group)
+
+	var previous map[string]string
+	if _, err := stateStore.Get(key, &previous); err != nil {
+		previous = nil
+	}
+
+	current := make(map[string]string, len(states))
+	for _, state := range states {
+		current[state.key] = state.value
+
+		before, ok := previous[state.key]
+		if !ok || before == state.value {
+			continue
+		}
+
+		attributes := map[string]interface{}{
+			"state":         state.name,
+			"previousValue": before,
+			"currentValue":  state.value,
+		}
+		for name, value := range state.attributes {
+			attributes[name] = value
+		}
+
+		metricChan <- newrelicMetricSender{
+			metadata: state.metadata,
+			event:    event.NewWithAttributes(fmt.Sprintf("%s from %s to %s", state.summary, before, state.value), event.NotificationEventCategory, attributes),
+		}
+	}
+
+	stateStore.Set(key, current)
+}
+
+// stateEventsGenerator returns a metrics generator that turns every row into
+// tracked states with toStates and sends the events of those that changed
+func stateEventsGenerator(group string, toStates func(map[string]interface{}) []trackedState) func(database.Rows, []*oracleMetric, chan<- newrelicMetricSender) error {
+	return func(rows database.Rows, _ []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+		columnNames, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve columns from rows")
+		}
+
+		var states []trackedState
+		for rows.Next() {
+			rowMap, err := scanRowMap(rows, columnNames)
+			if err != nil {
+				return err
+			}
+			states = append(states, toStates(rowMap)...)
+		}
+
+		sendStateChangeEvents(group, states, metricChan)
+		return nil
+	}
+}
+
+// stringValue returns a scanned column as a string
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+var oracleInstanceStateEvents = oracleMetricGroup{
+	name: "instance_state_events",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT
+			i.INST_ID,
+			TO_CHAR(i.STARTUP_TIME, 'YYYY-MM-DD HH24:MI:SS') AS STARTUP_TIME,
+			d.DATABASE_ROLE
+		FROM gv$instance i, gv$database d
+		WHERE i.INST_ID = d.INST_ID`
+	},
+	events: []string{"Instance restarted", "Database role changed"},
+	metricsGenerator: stateEventsGenerator("instance_state_events", func(row map[string]interface{}) []trackedState {
+		instanceID := getInstanceIDString(row["INST_ID"])
+		metadata := map[string]string{"instanceID": instanceID}
+		return []trackedState{
+			{
+				metadata: metadata,
+				key:      instanceID + ":startupTime",
+				name:     "startupTime",
+				value:    stringValue(row["STARTUP_TIME"]),
+				summary:  "Instance restarted",
+			},
+			{
+				metadata: metadata,
+				key:      instanceID + ":databaseRole",
+				name:     "databaseRole",
+				value:    stringValue(row["DATABASE_ROLE"]),
+				summary:  "Database role changed",
+			},
+		}
+	}),
+}
+
+var oraclePDBStateEvents = oracleMetricGroup{
+	name: "pdb_state_events",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		return `
+		SELECT INST_ID, NAME, OPEN_MODE
+		FROM gv$pdbs`
+	},
+	events: []string{"PDB open mode changed"},
+	metricsGenerator: stateEventsGenerator("pdb_state_events", func(row map[string]interface{}) []trackedState {
+		instanceID := getInstanceIDString(row["INST_ID"])
+		pdbName := stringValue(row["NAME"])
+		return []trackedState{
+			{
+				metadata:   map[string]string{"instanceID": instanceID},
+				key:        instanceID + ":" + pdbName,
+				name:       "openMode",
+				value:      stringValue(row["OPEN_MODE"]),
+				summary:    fmt.Sprintf("PDB %s open mode changed", pdbName),
+				attributes: map[string]interface{}{"pdbName": pdbName},
+			},
+		}
+	}),
+}
+
+var oracleTablespaceStateEvents = oracleMetricGroup{
+	name: "tablespace_state_events",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		query := `
+		SELECT TABLESPACE_NAME, STATUS
+		FROM DBA_TABLESPACES`
+
+		query += inWhitelist("TABLESPACE_NAME", true, false)
+		return query
+	},
+	events: []string{"Tablespace status changed"},
+	metricsGenerator: stateEventsGenerator("tablespace_state_events", func(row map[string]interface{}) []trackedState {
+		tablespace := stringValue(row["TABLESPACE_NAME"])
+		return []trackedState{
+			{
+				metadata: map[string]string{"tablespace": tablespace},
+				key:      tablespace,
+				name:     "status",
+				value:    stringValue(row["STATUS"]),
+				summary:  "Tablespace status changed",
+			},
+		}
+	}),
+}
+
+var oracleDatafileStateEvents = oracleMetricGroup{
+	name: "datafile_state_events",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		query := `
+		SELECT FILE_NAME, TABLESPACE_NAME, ONLINE_STATUS
+		FROM DBA_DATA_FILES`
+
+		query += inWhitelist("TABLESPACE_NAME", true, false)
+		return query
+	},
+	events: []string{"Datafile status changed"},
+	metricsGenerator: stateEventsGenerator("datafile_state_events", func(row map[string]interface{}) []trackedState {
+		tablespace := stringValue(row["TABLESPACE_NAME"])
+		datafile := stringValue(row["FILE_NAME"])
+		return []trackedState{
+			{
+				metadata:   map[string]string{"tablespace": tablespace},
+				key:        datafile,
+				name:       "onlineStatus",
+				value:      stringValue(row["ONLINE_STATUS"]),
+				summary:    "Datafile status changed",
+				attributes: map[string]interface{}{"datafile": datafile},
+			},
+		}
+	}),
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+func TestInstanceStateEvents(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "STARTUP_TIME", "DATABASE_ROLE"}
+
+	senders := collectMetricGroup(t, oracleInstanceStateEvents, sqlmock.NewRows(columns).
+		AddRow(1, "2024-01-01 10:00:00", "PRIMARY"))
+	if len(senders) != 0 {
+		t.Fatalf("Expected no events on the first run, got %d", len(senders))
+	}
+
+	senders = collectMetricGroup(t, oracleInstanceStateEvents, sqlmock.NewRows(columns).
+		AddRow(1, "2024-01-02 08:30:00", "PRIMARY"))
+
+	expected := []newrelicMetricSender{
+		{
+			metadata: map[string]string{"instanceID": "1"},
+			event: event.NewWithAttributes(
+				"Instance restarted from 2024-01-01 10:00:00 to 2024-01-02 08:30:00",
+				event.NotificationEventCategory,
+				map[string]interface{}{
+					"state":         "startupTime",
+					"previousValue": "2024-01-01 10:00:00",
+					"currentValue":  "2024-01-02 08:30:00",
+				},
+			),
+		},
+	}
+	if !reflect.DeepEqual(senders, expected) {
+		t.Errorf("Expected %+v, got %+v", expected[0].event, senders)
+	}
+
+	senders = collectMetricGroup(t, oracleInstanceStateEvents, sqlmock.NewRows(columns).
+		AddRow(1, "2024-01-02 08:30:00", "PHYSICAL STANDBY"))
+	if len(senders) != 1 || senders[0].event.Attributes["state"] != "databaseRole" || senders[0].event.Attributes["previousValue"] != "PRIMARY" {
+		t.Errorf("Expected a database role change event, got %+v", senders)
+	}
+}
+
+func TestDatafileStateEvents(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"FILE_NAME", "TABLESPACE_NAME", "ONLINE_STATUS"}
+
+	collectMetricGroup(t, oracleDatafileStateEvents, sqlmock.NewRows(columns).
+		AddRow("/u01/users01.dbf", "USERS", "ONLINE").
+		AddRow("/u01/users02.dbf", "USERS", "ONLINE"))
+
+	senders := collectMetricGroup(t, oracleDatafileStateEvents, sqlmock.NewRows(columns).
+		AddRow("/u01/users01.dbf", "USERS", "ONLINE").
+		AddRow("/u01/users02.dbf", "USERS", "OFFLINE"))
+
+	if len(senders) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(senders))
+	}
+	if senders[0].metadata["tablespace"] != "USERS" || senders[0].event.Attributes["datafile"] != "/u01/users02.dbf" || senders[0].event.Attributes["currentValue"] != "OFFLINE" {
+		t.Errorf("Unexpected event %+v %+v", senders[0].metadata, senders[0].event)
+	}
+}
+
+func TestInstanceStateEvents_EmptyResult(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	columns := []string{"INST_ID", "STARTUP_TIME", "DATABASE_ROLE"}
+
+	collectMetricGroup(t, oracleInstanceStateEvents, sqlmock.NewRows(columns).
+		AddRow(1, "2024-01-01 10:00:00", "PRIMARY"))
+
+	if senders := collectMetricGroup(t, oracleInstanceStateEvents, sqlmock.NewRows(columns)); len(senders) != 0 {
+		t.Fatalf("Expected no events on an empty result, got %d", len(senders))
+	}
+
+	// A value missing from the previous run is seen for the first time again
+	senders := collectMetricGroup(t, oracleInstanceStateEvents, sqlmock.NewRows(columns).
+		AddRow(1, "2024-01-02 08:30:00", "PRIMARY"))
+	if len(senders) != 0 {
+		t.Fatalf("Expected no events after an empty result, got %d", len(senders))
+	}
+}
+
+func TestPopulateMetrics_Events(t *testing.T) {
+	args = argumentList{
+		Hostname:    "testhost",
+		Port:        "1234",
+		ServiceName: "testServiceName",
+	}
+	defer func() { args = argumentList{} }()
+
+	i, _ := integration.New("oracletest", "0.0.1")
+	metricChan := make(chan newrelicMetricSender, 2)
+	metricChan <- newrelicMetricSender{
+		metadata: map[string]string{"instanceID": "1"},
+		event:    event.New("Database role changed from PRIMARY to PHYSICAL STANDBY", event.NotificationEventCategory),
+	}
+	metricChan <- newrelicMetricSender{
+		metadata: map[string]string{"tablespace": "USERS"},
+		event:    event.New("Tablespace status changed from ONLINE to OFFLINE", event.NotificationEventCategory),
+	}
+	close(metricChan)
+
+	populateMetrics(metricChan, i, map[string]string{"1": "one"})
+
+	marshalled, err := i.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedJSON := `{"name":"oracletest","protocol_version":"3","integration_version":"0.0.1","data":[{"entity":{"name":"one","type":"ora-instance","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[],"inventory":{},"events":[{"summary":"Database role changed from PRIMARY to PHYSICAL STANDBY","category":"notifications","attributes":{"reportingEndpoint":"testhost:1234"}}]},{"entity":{"name":"USERS","type":"ora-tablespace","id_attributes":[{"Key":"endpoint","Value":"testhost:1234"},{"Key":"serviceName","Value":"testServiceName"}]},"metrics":[],"inventory":{},"events":[{"summary":"Tablespace status changed from ONLINE to OFFLINE","category":"notifications","attributes":{"reportingEndpoint":"testhost:1234"}}]}]}`
+	if string(marshalled) != expectedJSON {
+		t.Errorf("Expected %s, got %s", expectedJSON, marshalled)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/nri-oracledb/src/database"
+)
+
+const (
+	datafileSample = "OracleDatafileSample"
+	// growthHistoryDays is how many daily used space samples are kept per tablespace
+	growthHistoryDays = 30
+	growthDayLayout   = "2006-01-02"
+)
+
+var oracleDatafiles = oracleMetricGroup{
+	name: "datafiles",
+	sqlQuery: func(metrics []*oracleMetric) string {
+		query := `
+		SELECT
+			f.TABLESPACE_NAME,
+			f.FILE_NAME,
+			f.BYTES,
+			CASE WHEN f.AUTOEXTENSIBLE = 'YES' THEN GREATEST(f.MAXBYTES, f.BYTES) ELSE f.BYTES END AS MAX_BYTES,
+			CASE WHEN f.AUTOEXTENSIBLE = 'YES' THEN 1 ELSE 0 END AS AUTOEXTENSIBLE,
+			f.INCREMENT_BY * t.BLOCK_SIZE AS INCREMENT_BYTES,
+			g.FREE_MB * 1024 * 1024 AS ASM_FREE_BYTES
+		FROM dba_data_files f
+		JOIN dba_tablespaces t ON t.TABLESPACE_NAME = f.TABLESPACE_NAME
+		LEFT JOIN v$asm_diskgroup g ON f.FILE_NAME LIKE '+' || g.NAME || '/%'`
+
+		query += inWhitelist("f.TABLESPACE_NAME", true, false)
+		return query
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "datafile.sizeInBytes",
+			identifier:    "BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "datafile.maxSizeInBytes",
+			identifier:    "MAX_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "datafile.autoextensible",
+			identifier:    "AUTOEXTENSIBLE",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "datafile.incrementInBytes",
+			identifier:    "INCREMENT_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "datafile.asmDiskGroupFreeInBytes",
+			identifier:    "ASM_FREE_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: keyedColumnMetricsGenerator(func(row map[string]interface{}) map[string]string {
+		return map[string]string{
+			"tablespace":                         stringValue(row["TABLESPACE_NAME"]),
+			sampleMetadataKey:                    datafileSample,
+			dimensionMetadataPrefix + "datafile": stringValue(row["FILE_NAME"]),
+		}
+	}),
+}
+
+// oracleTablespaceGrowth only needs a daily sample, so it runs as a slow group. The
+// space datafiles can still autoextend by is capped by the free space of their ASM
+// disk group, as a tablespace cannot grow past a full disk group
+var oracleTablespaceGrowth = oracleMetricGroup{
+	name: "tablespace_growth",
+	slow: true,
+	sqlQuery: func(metrics []*oracleMetric) string {
+		query := `
+		SELECT
+			f.TABLESPACE_NAME,
+			f.DATAFILES,
+			f.AUTOEXTENSIBLE_DATAFILES,
+			f.MAX_BYTES,
+			u.USED_SPACE * t.BLOCK_SIZE AS USED_BYTES
+		FROM (
+			SELECT
+				TABLESPACE_NAME,
+				SUM(DATAFILES) AS DATAFILES,
+				SUM(AUTOEXTENSIBLE_DATAFILES) AS AUTOEXTENSIBLE_DATAFILES,
+				SUM(BYTES + CASE WHEN ASM_FREE_BYTES IS NULL THEN EXTENSIBLE_BYTES ELSE LEAST(EXTENSIBLE_BYTES, ASM_FREE_BYTES) END) AS MAX_BYTES
+			FROM (
+				SELECT
+					f.TABLESPACE_NAME,
+					COUNT(*) AS DATAFILES,
+					SUM(CASE WHEN f.AUTOEXTENSIBLE = 'YES' THEN 1 ELSE 0 END) AS AUTOEXTENSIBLE_DATAFILES,
+					SUM(f.BYTES) AS BYTES,
+					SUM(CASE WHEN f.AUTOEXTENSIBLE = 'YES' THEN GREATEST(f.MAXBYTES, f.BYTES) - f.BYTES ELSE 0 END) AS EXTENSIBLE_BYTES,
+					MAX(g.FREE_MB) * 1024 * 1024 AS ASM_FREE_BYTES
+				FROM dba_data_files f
+				LEFT JOIN v$asm_diskgroup g ON f.FILE_NAME LIKE '+' || g.NAME || '/%'
+				GROUP BY f.TABLESPACE_NAME, g.NAME
+			)
+			GROUP BY TABLESPACE_NAME
+		) f
+		JOIN dba_tablespaces t ON t.TABLESPACE_NAME = f.TABLESPACE_NAME
+		JOIN dba_tablespace_usage_metrics u ON u.TABLESPACE_NAME = f.TABLESPACE_NAME`
+
+		query += inWhitelist("f.TABLESPACE_NAME", true, false)
+		return query
+	},
+
+	metrics: []*oracleMetric{
+		{
+			name:          "tablespace.datafiles",
+			identifier:    "DATAFILES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "tablespace.autoextensibleDatafiles",
+			identifier:    "AUTOEXTENSIBLE_DATAFILES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "tablespace.maxSizeInBytes",
+			identifier:    "MAX_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "tablespace.headroomInBytes",
+			identifier:    "HEADROOM_BYTES",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "tablespace.growthInBytesPerDay",
+			identifier:    "GROWTH_PER_DAY",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+		{
+			name:          "tablespace.daysUntilFull",
+			identifier:    "DAYS_UNTIL_FULL",
+			metricType:    metric.GAUGE,
+			defaultMetric: true,
+		},
+	},
+
+	metricsGenerator: tablespaceGrowthGenerator,
+}
+
+// growthSample is the used space of a tablespace on a day
+type growthSample struct {
+	Day       string  `json:"day"`
+	UsedBytes float64 `json:"usedBytes"`
+}
+
+// tablespaceGrowthGenerator reports the maximum size tablespaces can autoextend to
+// and the space left until then. The used space of every tablespace is kept once a day
+// in the state store, and its linear growth forecasts the days until it is full
+func tablespaceGrowthGenerator(rows database.Rows, metrics []*oracleMetric, metricChan chan<- newrelicMetricSender) error {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns from rows")
+	}
+
+	today := time.Now().UTC().Format(growthDayLayout)
+	for rows.Next() {
+		rowMap, err := scanRowMap(rows, columnNames)
+		if err != nil {
+			return err
+		}
+
+		tablespace := stringValue(rowMap["TABLESPACE_NAME"])
+		maxBytes, _ := toFloat64(sanitizedValue(rowMap["MAX_BYTES"]))
+		usedBytes, ok := toFloat64(sanitizedValue(rowMap["USED_BYTES"]))
+		if ok {
+			rowMap["HEADROOM_BYTES"] = maxBytes - usedBytes
+
+			key := stateKey("tablespace-growth", tablespace)
+			var samples []growthSample
+			if _, err := stateStore.Get(key, &samples); err != nil {
+				samples = nil
+			}
+			samples = recordGrowthSample(samples, today, usedBytes)
+			stateStore.Set(key, samples)
+
+			if growth, ok := linearGrowthPerDay(samples); ok {
+				rowMap["GROWTH_PER_DAY"] = growth
+				if growth > 0 {
+					rowMap["DAYS_UNTIL_FULL"] = (maxBytes - usedBytes) / growth
+				}
+			}
+		}
+
+		for _, metric := range metrics {
+			if value := rowMap[metric.identifier]; value != nil && metricEnabled(metric) {
+				metricChan <- newrelicMetricSender{
+					metric:   &newrelicMetric{name: metric.name, metricType: metric.metricType, value: value},
+					metadata: map[string]string{"tablespace": tablespace},
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordGrowthSample sets the used space of day, replacing an earlier sample of the
+// same day, and drops the samples beyond growthHistoryDays
+func recordGrowthSample(samples []growthSample, day string, usedBytes float64) []growthSample {
+	if len(samples) > 0 && samples[len(samples)-1].Day == day {
+		samples[len(samples)-1].UsedBytes = usedBytes
+	} else {
+		samples = append(samples, growthSample{Day: day, UsedBytes: usedBytes})
+	}
+
+	if len(samples) > growthHistoryDays {
+		samples = samples[len(samples)-growthHistoryDays:]
+	}
+	return samples
+}
+
+// linearGrowthPerDay returns the slope of the least squares line through the daily
+// samples, in bytes per day. At least two days are needed
+func linearGrowthPerDay(samples []growthSample) (float64, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	first, err := time.Parse(growthDayLayout, samples[0].Day)
+	if err != nil {
+		return 0, false
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, sample := range samples {
+		day, err := time.Parse(growthDayLayout, sample.Day)
+		if err != nil {
+			return 0, false
+		}
+		x := day.Sub(first).Hours() / 24
+		sumX += x
+		sumY += sample.UsedBytes
+		sumXY += x * sample.UsedBytes
+		sumXX += x * x
+	}
+
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+func Test_recordGrowthSample(t *testing.T) {
+	samples := recordGrowthSample(nil, "2024-01-01", 100)
+	samples = recordGrowthSample(samples, "2024-01-01", 150)
+	samples = recordGrowthSample(samples, "2024-01-02", 200)
+
+	expected := []growthSample{{"2024-01-01", 150}, {"2024-01-02", 200}}
+	if !reflect.DeepEqual(samples, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, samples)
+	}
+
+	for day := 3; day <= 40; day++ {
+		samples = recordGrowthSample(samples, time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC).Format(growthDayLayout), 0)
+	}
+	if len(samples) != growthHistoryDays || samples[len(samples)-1].Day != "2024-02-09" {
+		t.Errorf("Expected the last %d days, got %+v", growthHistoryDays, samples)
+	}
+}
+
+func Test_linearGrowthPerDay(t *testing.T) {
+	if _, ok := linearGrowthPerDay([]growthSample{{"2024-01-01", 100}}); ok {
+		t.Error("Expected no growth from a single sample")
+	}
+
+	growth, ok := linearGrowthPerDay([]growthSample{{"2024-01-01", 1000}, {"2024-01-02", 1100}, {"2024-01-04", 1300}})
+	if !ok || growth != 100 {
+		t.Errorf("Expected a growth of 100 bytes per day, got %f", growth)
+	}
+}
+
+func TestTablespaceGrowth(t *testing.T) {
+	stateStore = persist.NewInMemoryStore()
+	defer func() { stateStore = persist.NewInMemoryStore() }()
+
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(growthDayLayout)
+	stateStore.Set(stateKey("tablespace-growth", "USERS"), []growthSample{{yesterday, 4000}})
+
+	senders := collectMetricGroup(t, oracleTablespaceGrowth, sqlmock.NewRows([]string{"TABLESPACE_NAME", "DATAFILES", "AUTOEXTENSIBLE_DATAFILES", "MAX_BYTES", "USED_BYTES"}).
+		AddRow("USERS", 2, 1, 10000, 5000))
+
+	metrics := make(map[string]interface{})
+	for _, sender := range senders {
+		if sender.metadata["tablespace"] != "USERS" {
+			t.Errorf("Expected metric %s on tablespace USERS, got %v", sender.metric.name, sender.metadata)
+		}
+		metrics[sender.metric.name] = sender.metric.value
+	}
+
+	expected := map[string]interface{}{
+		"tablespace.datafiles":               int64(2),
+		"tablespace.autoextensibleDatafiles": int64(1),
+		"tablespace.maxSizeInBytes":          int64(10000),
+		"tablespace.headroomInBytes":         float64(5000),
+		"tablespace.growthInBytesPerDay":     float64(1000),
+		"tablespace.daysUntilFull":           float64(5),
+	}
+	if !reflect.DeepEqual(metrics, expected) {
+		t.Errorf("Expected %v, got %v", expected, metrics)
+	}
+}
+
+func TestTablespaceGrowth_ASMCap(t *testing.T) {
+	query := oracleTablespaceGrowth.sqlQuery(oracleTablespaceGrowth.metrics)
+	if !strings.Contains(query, "LEAST(EXTENSIBLE_BYTES, ASM_FREE_BYTES)") || !strings.Contains(query, "GROUP BY f.TABLESPACE_NAME, g.NAME") {
+		t.Errorf("Expected the autoextend space to be capped per ASM disk group, got %s", query)
+	}
+	if !oracleTablespaceGrowth.slow {
+		t.Error("Expected tablespace_growth to be a slow group")
+	}
+}
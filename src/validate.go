@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	argumentsSchemaFile   = "arguments.schema.json"
+	customQuerySchemaFile = "custom-query.schema.json"
+)
+
+// schemaFiles holds the published JSON schemas used by the -validate mode
+//
+//go:embed schema/*.json
+var schemaFiles embed.FS
+
+var (
+	yamlErrorLinePattern   = regexp.MustCompile(`line (\d+)`)
+	additionalPropsPattern = regexp.MustCompile(`^additionalProperties '([^']+)'`)
+)
+
+// validationProblem is a single issue found while validating the configuration.
+// source is either an argument name or a file path, and line is 0 when the
+// source has no meaningful line information
+type validationProblem struct {
+	source  string
+	line    int
+	message string
+}
+
+func (p validationProblem) String() string {
+	if p.line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.source, p.line, p.message)
+	}
+	return fmt.Sprintf("%s: %s", p.source, p.message)
+}
+
+// runValidation validates the arguments and the custom query file without
+// connecting to the database, printing every problem found. It returns the
+// process exit code
+func runValidation() int {
+	problems := validateConfiguration()
+	if len(problems) == 0 {
+		fmt.Println("Configuration is valid")
+		return 0
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem.String())
+	}
+	fmt.Fprintf(os.Stderr, "Found %d configuration problem(s)\n", len(problems))
+	return 1
+}
+
+// validateConfiguration checks the arguments against the arguments schema and the
+// metric group registry, and the CUSTOM_METRICS_CONFIG file against the custom query schema
+func validateConfiguration() []validationProblem {
+	problems := validateArguments()
+
+	// Decoding errors are already reported by validateArguments
+	if err := parseCustomQueryAllowlist(); err != nil {
+		customQueryAllowlist = nil
+	}
+
+	if args.CustomMetricsQuery != "" {
+		if err := checkCustomQuery(args.CustomMetricsQuery); err != nil {
+			problems = append(problems, validationProblem{source: "CUSTOM_METRICS_QUERY", message: err.Error()})
+		}
+	}
+
+	if args.CustomMetricsConfig != "" {
+		problems = append(problems, validateCustomMetricsFile(args.CustomMetricsConfig)...)
+	}
+
+	return problems
+}
+
+func validateArguments() []validationProblem {
+	var problems []validationProblem
+
+	document := map[string]interface{}{
+		"SERVICE_NAME":          args.ServiceName,
+		"USERNAME":              args.Username,
+		"HOSTNAME":              args.Hostname,
+		"PORT":                  args.Port,
+		"CONNECTION_STRING":     args.ConnectionString,
+		"MAX_OPEN_CONNECTIONS":  args.MaxOpenConnections,
+		"SESSIONS_TOP_N":        args.SessionsTopN,
+		"SESSION_IDLE_MINUTES":  args.SessionIdleMinutes,
+		"SLOW_METRICS_INTERVAL": args.SlowMetricsInterval,
+		"SEGMENTS_TOP_N":        args.SegmentsTopN,
+		"CONTENTION_TOP_N":      args.ContentionTopN,
+		"CUSTOM_METRICS_QUERY":  args.CustomMetricsQuery,
+		"CUSTOM_METRICS_CONFIG": args.CustomMetricsConfig,
+		"SYS_METRICS_SOURCE":    args.SysMetricsSource,
+	}
+
+	jsonArguments := map[string]string{
+		"TABLESPACES":            args.Tablespaces,
+		"SKIP_METRICS_GROUPS":    args.SkipMetricsGroups,
+		"INCLUDE_METRICS_GROUPS": args.IncludeMetricsGroups,
+		"ENABLE_METRICS_GROUPS":  args.EnableMetricsGroups,
+		"INCLUDE_METRICS":        args.IncludeMetrics,
+		"EXCLUDE_METRICS":        args.ExcludeMetrics,
+		"CUSTOM_QUERY_ALLOWLIST": args.CustomQueryAllowlist,
+		"SCHEMA_HEALTH_EXCLUDE":  args.SchemaHealthExclude,
+	}
+	for name, raw := range jsonArguments {
+		if raw == "" {
+			continue
+		}
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+			problems = append(problems, validationProblem{source: name, message: fmt.Sprintf("invalid JSON: %s", err)})
+			continue
+		}
+		document[name] = decoded
+	}
+
+	schema, err := compileSchema(argumentsSchemaFile)
+	if err != nil {
+		return append(problems, validationProblem{source: argumentsSchemaFile, message: err.Error()})
+	}
+
+	for _, leaf := range schemaViolations(schema, document) {
+		source, rest := splitPointer(leaf.InstanceLocation)
+		message := leaf.Message
+		if rest != "" {
+			message = fmt.Sprintf("%s: %s", rest, message)
+		}
+		problems = append(problems, validationProblem{source: source, message: message})
+	}
+
+	for _, argument := range []string{"SKIP_METRICS_GROUPS", "INCLUDE_METRICS_GROUPS", "ENABLE_METRICS_GROUPS"} {
+		groups, _ := document[argument].([]interface{})
+		for i, group := range groups {
+			if name, ok := group.(string); ok && name != "" && !isRegisteredMetricGroup(name) {
+				problems = append(problems, validationProblem{
+					source:  argument,
+					message: fmt.Sprintf("/%d: unknown metric group %q", i, name),
+				})
+			}
+		}
+	}
+
+	for _, argument := range []string{"INCLUDE_METRICS", "EXCLUDE_METRICS"} {
+		patterns, _ := document[argument].([]interface{})
+		for i, pattern := range patterns {
+			if p, ok := pattern.(string); ok {
+				if _, err := path.Match(p, ""); err != nil {
+					problems = append(problems, validationProblem{
+						source:  argument,
+						message: fmt.Sprintf("/%d: invalid glob pattern %q: %s", i, p, err),
+					})
+				}
+			}
+		}
+	}
+
+	sortProblems(problems)
+	return problems
+}
+
+func validateCustomMetricsFile(file string) []validationProblem {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return []validationProblem{{source: file, message: fmt.Sprintf("failed to read custom config file: %s", err)}}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(contents, &root); err != nil {
+		problem := validationProblem{source: file, message: err.Error()}
+		if match := yamlErrorLinePattern.FindStringSubmatch(err.Error()); match != nil {
+			problem.line, _ = strconv.Atoi(match[1])
+		}
+		return []validationProblem{problem}
+	}
+
+	schema, err := compileSchema(customQuerySchemaFile)
+	if err != nil {
+		return []validationProblem{{source: customQuerySchemaFile, message: err.Error()}}
+	}
+
+	var problems []validationProblem
+	for _, leaf := range schemaViolations(schema, yamlNodeValue(&root)) {
+		node := yamlNodeAt(&root, leaf.InstanceLocation)
+		if match := additionalPropsPattern.FindStringSubmatch(leaf.Message); match != nil {
+			if key := yamlMappingKey(node, match[1]); key != nil {
+				node = key
+			}
+		}
+
+		problem := validationProblem{source: file, message: leaf.Message}
+		if leaf.InstanceLocation != "" {
+			problem.message = fmt.Sprintf("%s: %s", leaf.InstanceLocation, leaf.Message)
+		}
+		if node != nil {
+			problem.line = node.Line
+		}
+		problems = append(problems, problem)
+	}
+
+	// Queries are also checked for read-only statements and CUSTOM_QUERY_ALLOWLIST
+	if queries := yamlNodeAt(&root, "/queries"); queries.Kind == yaml.SequenceNode {
+		for i, entry := range queries.Content {
+			if yamlMappingKey(entry, "query") == nil {
+				continue
+			}
+			value := yamlNodeAt(entry, "/query")
+			if value.Kind != yaml.ScalarNode {
+				continue
+			}
+			if err := checkCustomQuery(value.Value); err != nil {
+				problems = append(problems, validationProblem{
+					source:  file,
+					line:    value.Line,
+					message: fmt.Sprintf("/queries/%d/query: %s", i, err),
+				})
+			}
+		}
+	}
+
+	sortProblems(problems)
+	return problems
+}
+
+func compileSchema(name string) (*jsonschema.Schema, error) {
+	contents, err := schemaFiles.ReadFile("schema/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(name, bytes.NewReader(contents)); err != nil {
+		return nil, fmt.Errorf("loading schema: %w", err)
+	}
+
+	return compiler.Compile(name)
+}
+
+// schemaViolations validates document against schema and returns the leaf
+// errors, which are the ones carrying an actionable message
+func schemaViolations(schema *jsonschema.Schema, document interface{}) []*jsonschema.ValidationError {
+	err := schema.Validate(document)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []*jsonschema.ValidationError{{Message: err.Error()}}
+	}
+
+	var leaves []*jsonschema.ValidationError
+	var walk func(*jsonschema.ValidationError)
+	walk = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			leaves = append(leaves, ve)
+			return
+		}
+		for _, cause := range ve.Causes {
+			walk(cause)
+		}
+	}
+	walk(validationErr)
+
+	return leaves
+}
+
+// splitPointer splits a JSON pointer into its first token and the remainder
+func splitPointer(pointer string) (string, string) {
+	tokens := strings.SplitN(strings.TrimPrefix(pointer, "/"), "/", 2)
+	if len(tokens) == 1 {
+		return tokens[0], ""
+	}
+	return tokens[0], "/" + tokens[1]
+}
+
+func sortProblems(problems []validationProblem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].source != problems[j].source {
+			return problems[i].source < problems[j].source
+		}
+		if problems[i].line != problems[j].line {
+			return problems[i].line < problems[j].line
+		}
+		return problems[i].message < problems[j].message
+	})
+}
+
+// yamlNodeValue converts a YAML node into the generic JSON representation
+// expected by the schema validator
+func yamlNodeValue(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return yamlNodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlNodeValue(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			m[node.Content[i].Value] = yamlNodeValue(node.Content[i+1])
+		}
+		return m
+	case yaml.SequenceNode:
+		s := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			s = append(s, yamlNodeValue(item))
+		}
+		return s
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err == nil {
+			return b
+		}
+	case "!!int", "!!float":
+		var f float64
+		if err := node.Decode(&f); err == nil {
+			return f
+		}
+	}
+	return node.Value
+}
+
+// yamlNodeAt returns the node addressed by a JSON pointer, or the deepest
+// node that could be resolved
+func yamlNodeAt(root *yaml.Node, pointer string) *yaml.Node {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	if pointer == "" {
+		return node
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == token {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(token); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+			}
+		}
+
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+
+	return node
+}
+
+// yamlMappingKey returns the key node named key in a mapping node
+func yamlMappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
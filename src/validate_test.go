@@ -0,0 +1,95 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+)
+
+func Test_validateArguments(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     argumentList
+		expected []validationProblem
+	}{
+		{
+			name: "valid arguments",
+			args: argumentList{
+				Hostname:           "127.0.0.1",
+				Port:               "1521",
+				MaxOpenConnections: 5,
+				Tablespaces:        `["USERS"]`,
+				SkipMetricsGroups:  `["sga", "SYS_METRICS"]`,
+				SysMetricsSource:   "All",
+			},
+			expected: nil,
+		},
+		{
+			name: "unknown metric group",
+			args: argumentList{
+				Hostname:           "127.0.0.1",
+				Port:               "1521",
+				MaxOpenConnections: 5,
+				SkipMetricsGroups:  `["sga", "sgaa"]`,
+			},
+			expected: []validationProblem{
+				{source: "SKIP_METRICS_GROUPS", message: `/1: unknown metric group "sgaa"`},
+			},
+		},
+		{
+			name: "invalid JSON and values",
+			args: argumentList{
+				Hostname:           "127.0.0.1",
+				Port:               "port",
+				MaxOpenConnections: 0,
+				Tablespaces:        `["USERS"`,
+			},
+			expected: []validationProblem{
+				{source: "MAX_OPEN_CONNECTIONS", message: "must be >= 1 but found 0"},
+				{source: "PORT", message: "does not match pattern '^[0-9]+$'"},
+				{source: "TABLESPACES", message: "invalid JSON: unexpected end of JSON input"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args = tc.args
+			defer func() { args = argumentList{} }()
+
+			problems := validateArguments()
+			if !reflect.DeepEqual(tc.expected, problems) {
+				t.Errorf("unexpected problems: %s", pretty.Diff(tc.expected, problems))
+			}
+		})
+	}
+}
+
+func Test_validateCustomMetricsFile(t *testing.T) {
+	valid, err := filepath.Abs(filepath.Join("..", "test", "fixtures", "custom_query_multi.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if problems := validateCustomMetricsFile(valid); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	invalid, err := filepath.Abs(filepath.Join("..", "test", "fixtures", "custom_query_invalid.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []validationProblem{
+		{source: invalid, line: 4, message: "/queries/0/metric_types/one: does not match pattern '^(?i)(gauge|rate|delta|prate|pdelta|attribute)$'"},
+		{source: invalid, line: 7, message: "/queries/1: additionalProperties 'metric_type' not allowed"},
+		{source: invalid, line: 9, message: "/queries/2/query: only SELECT and WITH statements are allowed, got DELETE"},
+	}
+
+	problems := validateCustomMetricsFile(invalid)
+	if !reflect.DeepEqual(expected, problems) {
+		t.Errorf("unexpected problems: %s", pretty.Diff(expected, problems))
+	}
+}